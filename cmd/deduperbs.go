@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
-	"github.com/ryansann/k8sutil/k8s"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
@@ -55,7 +55,7 @@ func runDeduperbs(cmd *cobra.Command, args []string) {
 	if inputFileRbs == "" && inputFileCrbs == "" {
 		logrus.Debugf("using kubeconfig: %v", kubeConfig)
 
-		cli, err := k8s.GetClient(kubeConfig)
+		cli, err := factory.Client()
 		if err != nil {
 			logrus.Fatalf("error creating k8s client: %v", err)
 		}
@@ -104,19 +104,19 @@ func runDeduperbs(cmd *cobra.Command, args []string) {
 	}
 
 	if !dryRun {
-		cli, err := k8s.GetClient(kubeConfig)
+		cli, err := factory.Client()
 		if err != nil {
 			logrus.Fatalf("error creating k8s client: %v", err)
 		}
 
 		err = removeDupeRbs(cli, rbInd)
 		if err != nil {
-			logrus.Fatal("could not remove dupe rbs: %v", err)
+			logrus.Fatalf("could not remove dupe rbs: %v", err)
 		}
 
 		err = removeDupeCrbs(cli, crbInd)
 		if err != nil {
-			logrus.Fatal("could not remove dupe crbs: %v", err)
+			logrus.Fatalf("could not remove dupe crbs: %v", err)
 		}
 	}
 }
@@ -163,7 +163,7 @@ func findDupesFromFiles() (map[string][]string, map[string][]string) {
 }
 
 func findDupesFromK8s(cli *kubernetes.Clientset) (map[string][]string, map[string][]string) {
-	rbsList, err := cli.RbacV1().RoleBindings("").List(metav1.ListOptions{})
+	rbsList, err := cli.RbacV1().RoleBindings("").List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		logrus.Fatalf("could not retrieve RoleBindings from kubernetes, %v", err)
 	}
@@ -191,7 +191,7 @@ func findDupesFromK8s(cli *kubernetes.Clientset) (map[string][]string, map[strin
 		}
 	}
 
-	crbsList, err := cli.RbacV1().ClusterRoleBindings().List(metav1.ListOptions{})
+	crbsList, err := cli.RbacV1().ClusterRoleBindings().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		logrus.Fatalf("could not retrieve ClusterRoleBindings from kubernetes, %v", err)
 	}
@@ -336,7 +336,7 @@ func removeDupeRbs(cli *kubernetes.Clientset, ind map[string][]string) error {
 				ns, name := cmps[0], cmps[1]
 
 				logrus.Debugf("removing rb: %s/%s", ns, name)
-				err := cli.RbacV1().RoleBindings(ns).Delete(name, &metav1.DeleteOptions{})
+				err := cli.RbacV1().RoleBindings(ns).Delete(context.TODO(), name, metav1.DeleteOptions{})
 				if err != nil {
 					return err
 				}
@@ -355,7 +355,7 @@ func removeDupeCrbs(cli *kubernetes.Clientset, ind map[string][]string) error {
 				name := cmps[1]
 
 				logrus.Debugf("removing crb: %s", name)
-				err := cli.RbacV1().ClusterRoleBindings().Delete(name, &metav1.DeleteOptions{})
+				err := cli.RbacV1().ClusterRoleBindings().Delete(context.TODO(), name, metav1.DeleteOptions{})
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ryansann/k8sutil/diff"
+	"github.com/ryansann/k8sutil/k8s"
+	"github.com/ryansann/k8sutil/output"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> [<new>]",
+	Short: "diff compares two dumps, or a dump and the live cluster",
+	Long: "diff compares the resources in two dump outputs, in any output format or bundle, matching them by group, kind, namespace and name. " +
+		"With --live, the resources in <old> are compared to a fresh dump using the --config dump config file, with --clean, --redact " +
+		"and --redact-mode applied to it as they are by dump so that it matches how <old> was dumped. " +
+		"Added, removed and changed resources are reported along with the fields that changed.",
+	Args: cobra.RangeArgs(1, 2),
+	Run:  runDiff,
+}
+
+var (
+	diffLive            bool
+	diffConfigFile      string
+	diffOutput          string
+	diffIgnore          []string
+	diffIncludeVolatile bool
+	diffClean           bool
+	diffRedact          []string
+	diffRedactMode      string
+)
+
+func init() {
+	diffCmd.PersistentFlags().BoolVar(&diffLive, "live", false, "Compare <old> to a fresh dump of the cluster instead of <new>")
+	diffCmd.PersistentFlags().StringVar(&diffConfigFile, "config", "./dump.yaml", "Path to dump config file used with --live")
+	diffCmd.PersistentFlags().StringVarP(&diffOutput, "output", "o", "text", "Output format, one of: text, json")
+	diffCmd.PersistentFlags().StringSliceVar(&diffIgnore, "ignore", nil, "Paths to fields to ignore, in addition to volatile metadata")
+	diffCmd.PersistentFlags().BoolVar(&diffIncludeVolatile, "include-volatile", false, "Compare volatile metadata such as resourceVersion and managedFields")
+	diffCmd.PersistentFlags().BoolVar(&diffClean, "clean", false, "Remove server populated fields from the live dump, as dump --clean does")
+	diffCmd.PersistentFlags().StringSliceVar(&diffRedact, "redact", nil, "Redaction presets to apply to the live dump in addition to those in the config file, secrets and/or credentials")
+	diffCmd.PersistentFlags().StringVar(&diffRedactMode, "redact-mode", "", "How values in the live dump are redacted, placeholder or hash, overrides redact.mode in the config file")
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	if debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	logrus.Debug("running diff command")
+
+	if diffLive == (len(args) == 2) {
+		logrus.Fatal("diff requires either <old> and <new>, or <old> and --live")
+	}
+
+	old, err := output.Load(args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	var new []unstructured.Unstructured
+	if diffLive {
+		new, err = liveResources()
+	} else {
+		new, err = output.Load(args[1])
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	logrus.Debugf("comparing %v resources to %v resources", len(old), len(new))
+
+	// the matches reported by dump aren't part of the resources
+	ignore := append([]string{k8s.MatchedField}, diffIgnore...)
+	if !diffIncludeVolatile {
+		ignore = append(ignore, diff.VolatilePaths...)
+	}
+
+	res, err := diff.Resources(old, new, ignore)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	switch diffOutput {
+	case "text":
+		err = res.WriteText(os.Stdout)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(res)
+	default:
+		err = fmt.Errorf("unknown output format: %q, must be one of: text, json", diffOutput)
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// liveResources dumps the resources selected by the diff config file from the cluster, the clean and redact flags
+// override the config file as they do for dump
+func liveResources() ([]unstructured.Unstructured, error) {
+	c, err := loadDumpConfig(diffConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if diffClean {
+		c.Clean = true
+	}
+
+	c.Redact.Presets = append(c.Redact.Presets, diffRedact...)
+	if diffRedactMode != "" {
+		c.Redact.Mode = diffRedactMode
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid dump config: %v", err)
+	}
+
+	dumps, err := k8s.GetDumps(factory, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []unstructured.Unstructured
+	for _, dumped := range dumps {
+		items = append(items, dumped...)
+	}
+	return items, nil
+}
@@ -1,14 +1,22 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
 
 	"github.com/ryansann/k8sutil/config"
 	"github.com/ryansann/k8sutil/k8s"
+	"github.com/ryansann/k8sutil/output"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var dumpCmd = &cobra.Command{
@@ -20,51 +28,278 @@ var dumpCmd = &cobra.Command{
 
 var (
 	dumpConfigFile string
+	dumpPageSize   int64
+	dumpOutput     string
+	dumpOutDir     string
+	dumpOutTar     string
+	dumpClean      bool
+	dumpWorkers    int
+	dumpAll        bool
+	dumpInclude    []string
+	dumpExclude    []string
+	dumpWatch      bool
+	dumpRedact     []string
+	dumpRedactMode string
+	dumpResource   string
+	dumpWhere      []string
+	dumpWhereAny   []string
 	cfg            config.DumpCommand
 )
 
+const (
+	defaultDumpAllWorkers = 5
+)
+
 func init() {
 	dumpCmd.PersistentFlags().StringVar(&dumpConfigFile, "config", "./dump.yaml", "Path to dump config file")
+	dumpCmd.PersistentFlags().Int64Var(&dumpPageSize, "page-size", 0, "Number of resources to retrieve per list request, overrides pageSize in the config file (default 500)")
+	dumpCmd.PersistentFlags().StringVarP(&dumpOutput, "output", "o", output.FormatJSON, "Output format, one of: "+strings.Join(output.Formats, ", "))
+	dumpCmd.PersistentFlags().StringVar(&dumpOutDir, "out-dir", "", "Write each resource to its own file under this directory instead of stdout")
+	dumpCmd.PersistentFlags().StringVar(&dumpOutTar, "out-tar", "", "Write each resource to its own file in this gzipped tarball instead of stdout")
+	dumpCmd.PersistentFlags().BoolVar(&dumpClean, "clean", false, "Remove server populated fields (uid, resourceVersion, managedFields, creationTimestamp, status, etc.) so output can be re-applied")
+	dumpCmd.PersistentFlags().IntVar(&dumpWorkers, "workers", 0, fmt.Sprintf("Number of dumps to list concurrently (default 1, or %v with --all)", defaultDumpAllWorkers))
+	dumpCmd.PersistentFlags().BoolVar(&dumpAll, "all", false, "Dump every listable resource type, the config file is only read if --config is set")
+	// shadows the global flag to add the shorthand, it can't be global since mocksecrets uses -n for --num-secrets
+	dumpCmd.PersistentFlags().StringVarP(&kubeNamespace, "namespace", "n", "", namespaceUsage)
+	dumpCmd.PersistentFlags().StringSliceVar(&dumpInclude, "include", nil, "Resources to dump with --all, by name, short name, kind or <resource>.<group> (default all)")
+	dumpCmd.PersistentFlags().StringSliceVar(&dumpExclude, "exclude", []string{"events"}, "Resources to skip with --all, by name, short name, kind or <resource>.<group>")
+	dumpCmd.PersistentFlags().StringSliceVar(&dumpRedact, "redact", nil, "Redaction presets to apply in addition to those in the config file, secrets and/or credentials")
+	dumpCmd.PersistentFlags().StringVar(&dumpRedactMode, "redact-mode", "", "How values are redacted, placeholder or hash, overrides redact.mode in the config file (default placeholder)")
+	dumpCmd.PersistentFlags().StringVar(&dumpResource, "resource", "", "Resource to dump in addition to those in the config file, as <resource>[.<version>.<group>], the config file is only read if --config is set")
+	dumpCmd.PersistentFlags().StringArrayVar(&dumpWhere, "where", nil, "Filter the --resource dump by <key><op><value>, all must match, op is one of: "+strings.Join(whereOps, " "))
+	dumpCmd.PersistentFlags().StringArrayVar(&dumpWhereAny, "where-any", nil, "Filter the --resource dump by <key><op><value>, at least one must match, op is one of: "+strings.Join(whereOps, " "))
+	dumpCmd.PersistentFlags().BoolVarP(&dumpWatch, "watch", "w", false, "After listing, watch the dumped resources and print changes to them as ndjson events")
 }
 
 func initDump(cmd *cobra.Command, args []string) {
-	cobra.OnInitialize(initConfig)
-}
+	if (!dumpAll && dumpResource == "") || cmd.Flags().Changed("config") {
+		initConfig()
+	}
 
-func initConfig() {
-	logrus.Debugf("using config file: %v", dumpConfigFile)
+	if dumpResource != "" {
+		dump, err := inlineDump(dumpResource, kubeNamespace, dumpWhere, dumpWhereAny)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		cfg.Dumps = append(cfg.Dumps, dump)
+	} else if len(dumpWhere) > 0 || len(dumpWhereAny) > 0 {
+		logrus.Fatal("--where and --where-any can only be used with --resource")
+	}
+
+	if cmd.Flags().Changed("page-size") {
+		cfg.PageSize = dumpPageSize
+	}
+
+	if dumpClean {
+		cfg.Clean = true
+	}
+
+	cfg.Redact.Presets = append(cfg.Redact.Presets, dumpRedact...)
+	if dumpRedactMode != "" {
+		cfg.Redact.Mode = dumpRedactMode
+	}
 
-	viper.SetConfigFile(dumpConfigFile)
+	if dumpAll {
+		// flags override the all section of the config file, if there is one
+		if cfg.All == nil {
+			cfg.All = &config.DumpAll{Exclude: dumpExclude}
+		}
+		if cmd.Flags().Changed("namespace") {
+			cfg.All.Namespace = kubeNamespace
+		}
+		if cmd.Flags().Changed("include") {
+			cfg.All.Include = dumpInclude
+		}
+		if cmd.Flags().Changed("exclude") {
+			cfg.All.Exclude = dumpExclude
+		}
+
+		if cfg.Workers == 0 {
+			cfg.Workers = defaultDumpAllWorkers
+		}
+	}
 
-	err := viper.ReadInConfig()
+	if cmd.Flags().Changed("workers") {
+		cfg.Workers = dumpWorkers
+	}
+
+	err := cfg.Validate()
 	if err != nil {
-		logrus.Fatal(err)
+		logrus.Fatalf("invalid dump config: %v", err)
 	}
+}
 
-	err = viper.Unmarshal(&cfg)
+func initConfig() {
+	var err error
+	cfg, err = loadDumpConfig(dumpConfigFile)
 	if err != nil {
 		logrus.Fatal(err)
 	}
 }
 
-func runDump(cmd *cobra.Command, args []string) {
-	cobra.OnInitialize(initConfig)
+// loadDumpConfig reads a dump config file, unknown keys are rejected so typos don't silently change what is dumped
+func loadDumpConfig(file string) (config.DumpCommand, error) {
+	logrus.Debugf("using config file: %v", file)
+
+	var c config.DumpCommand
+
+	v := viper.New()
+	v.SetConfigFile(file)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return c, err
+	}
+
+	err = v.UnmarshalExact(&c)
+	return c, err
+}
+
+// whereOps maps the operators accepted by --where and --where-any to filter ops, longer operators are listed first
+// so that they are matched before their prefixes
+var whereOps = []string{"!=", "=~", ">=", "<=", "=", ">", "<"}
+
+var whereOpFilters = map[string]string{
+	"!=": config.OpNe,
+	"=~": config.OpRegex,
+	">=": config.OpGe,
+	"<=": config.OpLe,
+	"=":  config.OpEq,
+	">":  config.OpGt,
+	"<":  config.OpLt,
+}
+
+// inlineDump builds a dump from the --resource, --namespace, --where and --where-any flags
+func inlineDump(resource, namespace string, where, whereAny []string) (config.Dump, error) {
+	dump := config.Dump{Namespace: namespace}
+
+	// like kubectl, <resource>.<version>.<group> is only fully qualified if the version looks like one,
+	// otherwise everything after the first dot is the group
+	gvr, gr := schema.ParseResourceArg(resource)
+	if gvr != nil && versionRegex.MatchString(gvr.Version) {
+		dump.GVR = *gvr
+	} else {
+		dump.GVR = gr.WithVersion("")
+	}
+
+	for _, w := range where {
+		e, err := parseWhere(w)
+		if err != nil {
+			return dump, err
+		}
+		dump.Filters.Ands = append(dump.Filters.Ands, e)
+	}
 
+	for _, w := range whereAny {
+		e, err := parseWhere(w)
+		if err != nil {
+			return dump, err
+		}
+		dump.Filters.Ors = append(dump.Filters.Ors, e)
+	}
+
+	return dump, nil
+}
+
+var versionRegex = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// parseWhere parses a <key><op><value> filter expression, operators inside gjson queries such as
+// subjects.#(kind=="User").name are part of the key
+func parseWhere(expr string) (config.FilterElement, error) {
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+			continue
+		case '(', '[', '{':
+			depth++
+			continue
+		case ')', ']', '}':
+			depth--
+			continue
+		}
+
+		if depth > 0 {
+			continue
+		}
+
+		for _, op := range whereOps {
+			if strings.HasPrefix(expr[i:], op) {
+				if i == 0 {
+					return config.FilterElement{}, fmt.Errorf("invalid filter %q, missing key", expr)
+				}
+				return config.FilterElement{Key: expr[:i], Op: whereOpFilters[op], Value: expr[i+len(op):]}, nil
+			}
+		}
+	}
+
+	return config.FilterElement{}, fmt.Errorf("invalid filter %q, expected <key><op><value> with op one of: %v", expr, strings.Join(whereOps, " "))
+}
+
+func runDump(cmd *cobra.Command, args []string) {
 	if debug {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 
 	logrus.Debug("running dump command")
 
-	dumps, err := k8s.GetDumps(kubeConfig, cfg)
+	if dumpWatch {
+		watchDumps(cmd)
+		return
+	}
+
+	p, err := newDumpPrinter()
 	if err != nil {
 		logrus.Fatal(err)
 	}
 
-	dbytes, err := json.MarshalIndent(dumps, "", "  ")
+	err = k8s.StreamDumps(factory, cfg, p)
 	if err != nil {
 		logrus.Fatal(err)
 	}
 
-	fmt.Println(string(dbytes))
+	err = p.Flush()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// newDumpPrinter returns the printer selected by the dump output flags
+func newDumpPrinter() (output.Printer, error) {
+	if dumpOutDir == "" && dumpOutTar == "" {
+		return output.New(dumpOutput, os.Stdout)
+	}
+
+	if dumpOutDir != "" && dumpOutTar != "" {
+		return nil, fmt.Errorf("only one of --out-dir and --out-tar can be set")
+	}
+
+	context, err := factory.CurrentContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if dumpOutDir != "" {
+		return output.NewDirBundle(dumpOutDir, context)
+	}
+	return output.NewTarBundle(dumpOutTar, context)
+}
+
+// watchDumps prints changes to the dumped resources as ndjson events until interrupted
+func watchDumps(cmd *cobra.Command) {
+	if dumpOutDir != "" || dumpOutTar != "" || cmd.Flags().Changed("output") {
+		logrus.Fatal("--watch always prints ndjson events to stdout, it can't be used with --output, --out-dir or --out-tar")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	enc := json.NewEncoder(os.Stdout)
+	err := k8s.WatchDumps(ctx, factory, cfg, func(e k8s.WatchEvent) error {
+		return enc.Encode(e)
+	})
+	if err != nil {
+		logrus.Fatal(err)
+	}
 }
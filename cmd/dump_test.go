@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    config.FilterElement
+		wantErr bool
+	}{
+		{expr: "metadata.name=web", want: config.FilterElement{Key: "metadata.name", Op: config.OpEq, Value: "web"}},
+		{expr: "metadata.name!=web", want: config.FilterElement{Key: "metadata.name", Op: config.OpNe, Value: "web"}},
+		{expr: "metadata.name=~^web-", want: config.FilterElement{Key: "metadata.name", Op: config.OpRegex, Value: "^web-"}},
+		{expr: "spec.replicas>=2", want: config.FilterElement{Key: "spec.replicas", Op: config.OpGe, Value: "2"}},
+		{expr: "spec.replicas<=2", want: config.FilterElement{Key: "spec.replicas", Op: config.OpLe, Value: "2"}},
+		{expr: "spec.replicas>2", want: config.FilterElement{Key: "spec.replicas", Op: config.OpGt, Value: "2"}},
+		{expr: "spec.replicas<2", want: config.FilterElement{Key: "spec.replicas", Op: config.OpLt, Value: "2"}},
+		{expr: "metadata.name=", want: config.FilterElement{Key: "metadata.name", Op: config.OpEq, Value: ""}},
+		{expr: "metadata.name=a=b", want: config.FilterElement{Key: "metadata.name", Op: config.OpEq, Value: "a=b"}},
+		{expr: "metadata.name=a!=b", want: config.FilterElement{Key: "metadata.name", Op: config.OpEq, Value: "a!=b"}},
+		{
+			expr: `subjects.#(kind=="User").name=u-4qdsz`,
+			want: config.FilterElement{Key: `subjects.#(kind=="User").name`, Op: config.OpEq, Value: "u-4qdsz"},
+		},
+		{
+			expr: `subjects.#(name!="a")#.kind!=Group`,
+			want: config.FilterElement{Key: `subjects.#(name!="a")#.kind`, Op: config.OpNe, Value: "Group"},
+		},
+		{
+			expr: `metadata.labels.a\=b=c`,
+			want: config.FilterElement{Key: `metadata.labels.a\=b`, Op: config.OpEq, Value: "c"},
+		},
+		{expr: "=web", wantErr: true},
+		{expr: "metadata.name", wantErr: true},
+		{expr: `subjects.#(kind=="User"`, wantErr: true},
+		{expr: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseWhere(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWhere(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseWhere(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineDump(t *testing.T) {
+	tests := []struct {
+		name      string
+		resource  string
+		namespace string
+		where     []string
+		whereAny  []string
+		want      config.Dump
+		wantErr   bool
+	}{
+		{
+			name:     "resource",
+			resource: "pods",
+			want:     config.Dump{GVR: schema.GroupVersionResource{Resource: "pods"}},
+		},
+		{
+			name:      "resource and group",
+			resource:  "deployments.apps",
+			namespace: "web",
+			want:      config.Dump{Namespace: "web", GVR: schema.GroupVersionResource{Group: "apps", Resource: "deployments"}},
+		},
+		{
+			name:     "fully qualified",
+			resource: "deployments.v1.apps",
+			want:     config.Dump{GVR: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		},
+		{
+			name:     "group that looks like a version",
+			resource: "widgets.example.com",
+			want:     config.Dump{GVR: schema.GroupVersionResource{Group: "example.com", Resource: "widgets"}},
+		},
+		{
+			name:     "beta version",
+			resource: "cronjobs.v1beta1.batch",
+			want:     config.Dump{GVR: schema.GroupVersionResource{Group: "batch", Version: "v1beta1", Resource: "cronjobs"}},
+		},
+		{
+			name:     "filters",
+			resource: "pods",
+			where:    []string{"metadata.name=~^web-", "spec.nodeName!=node-1"},
+			whereAny: []string{"status.phase=Failed", "status.phase=Unknown"},
+			want: config.Dump{
+				GVR: schema.GroupVersionResource{Resource: "pods"},
+				Filters: config.Filter{
+					Ands: []config.FilterElement{
+						{Key: "metadata.name", Op: config.OpRegex, Value: "^web-"},
+						{Key: "spec.nodeName", Op: config.OpNe, Value: "node-1"},
+					},
+					Ors: []config.FilterElement{
+						{Key: "status.phase", Op: config.OpEq, Value: "Failed"},
+						{Key: "status.phase", Op: config.OpEq, Value: "Unknown"},
+					},
+				},
+			},
+		},
+		{
+			name:     "invalid where",
+			resource: "pods",
+			where:    []string{"metadata.name"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid where any",
+			resource: "pods",
+			whereAny: []string{"=web"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inlineDump(tt.resource, tt.namespace, tt.where, tt.whereAny)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("inlineDump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inlineDump() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
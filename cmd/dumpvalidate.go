@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ryansann/k8sutil/k8s"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var dumpValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "validate checks a dump config file for errors without dumping anything",
+	Long: "validate decodes the --config dump config file strictly, rejecting unknown keys, and checks resources, selectors, " +
+		"filter paths and ops, columns and redaction rules. Unless --offline is set, it also checks that every dump " +
+		"resolves to a resource served by the cluster.",
+	Args: cobra.NoArgs,
+	Run:  runDumpValidate,
+}
+
+var (
+	dumpValidateOffline bool
+)
+
+func init() {
+	dumpValidateCmd.Flags().BoolVar(&dumpValidateOffline, "offline", false, "Skip the checks that require contacting the cluster")
+	dumpCmd.AddCommand(dumpValidateCmd)
+}
+
+func runDumpValidate(cmd *cobra.Command, args []string) {
+	if debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	logrus.Debug("running dump validate command")
+
+	c, err := loadDumpConfig(dumpConfigFile)
+	if err != nil {
+		logrus.Fatalf("invalid dump config: %v", err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		logrus.Fatalf("invalid dump config: %v", err)
+	}
+
+	if !dumpValidateOffline {
+		resolver, err := k8s.NewResolver(factory)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		err = resolver.Validate(c)
+		if err != nil {
+			logrus.Fatalf("invalid dump config: %v", err)
+		}
+	}
+
+	fmt.Printf("%v is valid\n", dumpConfigFile)
+}
@@ -1,12 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"sync"
 	"time"
 
-	"github.com/ryansann/k8sutil/k8s"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
@@ -34,7 +34,7 @@ func init() {
 	mockSecretsCmd.PersistentFlags().IntVarP(&numSecretWorkers, "num-workers", "w", 10, "Number of workers to create secrets")
 	mockSecretsCmd.PersistentFlags().IntVarP(&secretSize, "secret-size", "s", 10, "How large the generated secret data is")
 	mockSecretsCmd.PersistentFlags().IntVar(&seqStart, "seq-start", 1, "Where to start the sequence for secret naming, e.g. secret-<seq-start>")
-	mockSecretsCmd.PersistentFlags().StringVar(&namespace, "ns", "default", "Namespace to create secrets in")
+	mockSecretsCmd.PersistentFlags().StringVar(&namespace, "ns", "", "Namespace to create secrets in (default the --namespace or kubeconfig context's namespace)")
 }
 
 func runMockSecrets(cmd *cobra.Command, args []string) {
@@ -45,15 +45,22 @@ func runMockSecrets(cmd *cobra.Command, args []string) {
 	logrus.Debug("running mocksecrets command")
 
 	logrus.Debugf("using kubeconfig: %v", kubeConfig)
-	cli, err := k8s.GetClient(kubeConfig)
+	cli, err := factory.Client()
 	if err != nil {
 		logrus.Fatal(err)
 	}
 
+	if namespace == "" {
+		namespace, err = factory.Namespace()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
 	// check if namespace exists, create it if it doesn't
-	_, err = cli.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	_, err = cli.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
 	if errors.IsNotFound(err) { // create if not found
-		ns, err := cli.CoreV1().Namespaces().Create(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		ns, err := cli.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{})
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -80,13 +87,12 @@ func runMockSecrets(cmd *cobra.Command, args []string) {
 	for j := 1; j <= numSecretWorkers; j++ {
 		go func(w int) {
 			logrus.Debugf("starting worker %v", w)
-			workerCli, _ := k8s.GetClient(kubeConfig)
 			defer wg.Done()
 			for i := range jobs {
 				secretNum := seqStart + i
 				logrus.Debugf("worker %v creating secret %v", w, secretNum)
 				s := genRandomSecret(secretNum)
-				_, err := workerCli.CoreV1().Secrets(namespace).Create(&s)
+				_, err := cli.CoreV1().Secrets(namespace).Create(context.TODO(), &s, metav1.CreateOptions{})
 				if err != nil {
 					e <- err
 				}
@@ -143,7 +149,7 @@ func batchGetSecrets(cli *kubernetes.Clientset, ns string) ([]corev1.Secret, err
 	var secrets []corev1.Secret
 	var continueToken string
 	for {
-		secretsList, err := cli.CoreV1().Secrets(ns).List(metav1.ListOptions{
+		secretsList, err := cli.CoreV1().Secrets(ns).List(context.TODO(), metav1.ListOptions{
 			Limit:    secretBatchSize,
 			Continue: continueToken,
 		})
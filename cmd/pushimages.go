@@ -121,7 +121,7 @@ func runPushImages(cmd *cobra.Command, args []string) {
 
 				res, err := ioutil.ReadAll(rcls)
 				if err != nil {
-					logrus.Error("i/o error: %v", err)
+					logrus.Errorf("i/o error: %v", err)
 					continue
 				}
 
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ryansann/k8sutil/diff"
+	"github.com/ryansann/k8sutil/k8s"
+	"github.com/ryansann/k8sutil/output"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <dump>",
+	Short: "restore creates or applies the resources in a dump to the kubernetes cluster",
+	Long: "restore reads a dump in any output format, or a bundle created with --out-dir or --out-tar, removes server populated fields " +
+		"and creates the resources in the cluster, or applies them with --server-side. Namespaces are restored first, then " +
+		"CustomResourceDefinitions, then everything else. A result is reported for every resource. Resources managed by a " +
+		"controller are skipped, since the restored controller recreates them. With --dry-run=server, resources " +
+		"in namespaces or of CustomResourceDefinitions that don't exist yet are skipped, since they're only created as a dry run. " +
+		"Resources with redacted values are skipped unless --allow-redacted is set.",
+	Args: cobra.ExactArgs(1),
+	Run:  runRestore,
+}
+
+var (
+	restoreNamespaceMap map[string]string
+	restoreServerSide   bool
+	restoreFieldManager string
+	restoreForce        bool
+	restoreDryRun       string
+	restoreAllowRedact  bool
+	restoreOutput       string
+)
+
+func init() {
+	restoreCmd.PersistentFlags().StringToStringVar(&restoreNamespaceMap, "namespace-map", nil, "Namespaces to rename when restoring, e.g. old=new,other=renamed")
+	restoreCmd.PersistentFlags().BoolVar(&restoreServerSide, "server-side", false, "Server side apply resources instead of creating them, existing resources are updated")
+	restoreCmd.PersistentFlags().StringVar(&restoreFieldManager, "field-manager", "k8sutil", "Field manager used to create or apply resources")
+	restoreCmd.PersistentFlags().BoolVar(&restoreForce, "force-conflicts", false, "Take ownership of fields owned by other managers with --server-side")
+	restoreCmd.PersistentFlags().StringVar(&restoreDryRun, "dry-run", "none", "Must be none or server, with server requests are validated by the api server but not persisted")
+	restoreCmd.PersistentFlags().BoolVar(&restoreAllowRedact, "allow-redacted", false, "Restore resources with values redacted by dump, writing the REDACTED placeholders and sha256 hashes to the cluster")
+	restoreCmd.PersistentFlags().StringVarP(&restoreOutput, "output", "o", "text", "Output format of the results, one of: text, json")
+}
+
+// restoreResult is the json output for a single restored resource
+type restoreResult struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	if debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	logrus.Debug("running restore command")
+
+	if restoreDryRun != "none" && restoreDryRun != "server" {
+		logrus.Fatalf("invalid --dry-run: %q, must be none or server", restoreDryRun)
+	}
+
+	if restoreOutput != "text" && restoreOutput != "json" {
+		logrus.Fatalf("unknown output format: %q, must be one of: text, json", restoreOutput)
+	}
+
+	items, err := output.Load(args[0])
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	logrus.Debugf("restoring %v resources", len(items))
+
+	opts := k8s.RestoreOptions{
+		NamespaceMap:  restoreNamespaceMap,
+		ServerSide:    restoreServerSide,
+		FieldManager:  restoreFieldManager,
+		Force:         restoreForce,
+		DryRun:        restoreDryRun == "server",
+		AllowRedacted: restoreAllowRedact,
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	enc := json.NewEncoder(os.Stdout)
+	if restoreOutput == "text" {
+		fmt.Fprintln(tw, "RESULT\tRESOURCE\tERROR")
+	}
+
+	var failed, skipped int
+	err = k8s.Restore(factory, items, opts, func(res k8s.RestoreResult) {
+		r := restoreResult{ID: diff.ID(res.Item), Action: res.Action}
+		if res.Err != nil {
+			r.Error = res.Err.Error()
+		}
+
+		switch res.Action {
+		case k8s.RestoreFailed:
+			failed++
+		case k8s.RestoreSkipped:
+			skipped++
+		}
+
+		if restoreOutput == "json" {
+			err := enc.Encode(r)
+			if err != nil {
+				logrus.Error(err)
+			}
+			return
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Action, r.ID, r.Error)
+	})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if skipped > 0 {
+		logrus.Warnf("skipped %v resources managed by a controller, with redacted values or that need a namespace or custom "+
+			"resource definition only created by the dry run", skipped)
+	}
+
+	if failed > 0 {
+		logrus.Fatalf("failed to restore %v of %v resources", failed, len(items))
+	}
+}
@@ -1,40 +1,70 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/ryansann/k8sutil/k8s"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"os"
-	"os/user"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "k8sutil",
-	Short: "k8sutil performs helper operations on a kubernetes cluster",
-	Run:   run,
+	Use:              "k8sutil",
+	Short:            "k8sutil performs helper operations on a kubernetes cluster",
+	PersistentPreRun: initFactory,
+	Run:              run,
 }
 
 var (
-	kubeConfig string
-	debug      bool
+	kubeConfig            string
+	kubeContext           string
+	kubeCluster           string
+	kubeUser              string
+	kubeNamespace         string
+	kubeServer            string
+	inCluster             bool
+	insecureSkipTLSVerify bool
+	certificateAuthority  string
+	qps                   float32
+	burst                 int
+	timeout               time.Duration
+	impersonate           string
+	impersonateGroups     []string
+	impersonateUID        string
+	debug                 bool
+	// factory creates the clients shared by every command
+	factory *k8s.Factory
 )
 
+// namespaceUsage is shared by the global --namespace flag and dump's, which shadows it to add the -n shorthand
+const namespaceUsage = "Namespace to use instead of the kubeconfig context's for resources without one, dump --all and --resource " +
+	"dump every namespace unless it's set (-n with dump)"
+
 func init() {
 	rootCmd.AddCommand(
 		dumpCmd,
 		mockSecretsCmd,
 		pushImagesCmd,
 		deduperbsCmd,
+		diffCmd,
+		restoreCmd,
 	)
-	rootCmd.PersistentFlags().StringVarP(&kubeConfig, "kube-config", "c", "", "Kubeconfig file for cluster")
+	rootCmd.PersistentFlags().StringVarP(&kubeConfig, "kube-config", "c", "", "Kubeconfig file for cluster (default the files in $KUBECONFIG or ~/.kube/config)")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Kubeconfig context to use instead of the current context")
+	rootCmd.PersistentFlags().StringVar(&kubeCluster, "cluster", "", "Kubeconfig cluster to use instead of the context's")
+	rootCmd.PersistentFlags().StringVar(&kubeUser, "user", "", "Kubeconfig user to use instead of the context's")
+	rootCmd.PersistentFlags().StringVar(&kubeNamespace, "namespace", "", namespaceUsage)
+	rootCmd.PersistentFlags().StringVar(&kubeServer, "server", "", "Address of the api server to use instead of the cluster's")
+	rootCmd.PersistentFlags().BoolVar(&inCluster, "in-cluster", false, "Use the service account of the pod k8sutil runs in, the default in a pod without a kubeconfig")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "Don't verify the api server's certificate, the connection is vulnerable to man-in-the-middle attacks")
+	rootCmd.PersistentFlags().StringVar(&certificateAuthority, "certificate-authority", "", "Path to a CA certificate file used to verify the api server's certificate instead of the kubeconfig's")
+	rootCmd.PersistentFlags().Float32Var(&qps, "qps", k8s.DefaultQPS, "Maximum requests per second to the api server, shared by all requests of a command, negative disables the limit")
+	rootCmd.PersistentFlags().IntVar(&burst, "burst", k8s.DefaultBurst, "Maximum burst of requests to the api server above --qps")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", k8s.DefaultTimeout, "Timeout of each request to the api server except watches, 0 means no timeout")
+	rootCmd.PersistentFlags().StringVar(&impersonate, "as", "", "Username to impersonate for every request, e.g. a Rancher user id")
+	rootCmd.PersistentFlags().StringArrayVar(&impersonateGroups, "as-group", nil, "Group to impersonate along with --as, can be repeated")
+	rootCmd.PersistentFlags().StringVar(&impersonateUID, "as-uid", "", "UID to impersonate along with --as")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
-
-	if kubeConfig == "" {
-		kubeConfig = os.Getenv("KUBECONFIG")
-	}
-	if kubeConfig == "" {
-		usr, _ := user.Current()
-		kubeConfig = usr.HomeDir + "/.kube/config"
-	}
 }
 
 // run executes the steps required to dump resources
@@ -47,6 +77,32 @@ func run(cmd *cobra.Command, args []string) {
 	logrus.Debugf("using kubeconfig: %s", kubeConfig)
 }
 
+// initFactory creates the client factory from the global flags before any command runs
+func initFactory(cmd *cobra.Command, args []string) {
+	factory = k8s.NewFactory(clientOptions())
+}
+
+// clientOptions returns the options clients connect to the cluster with, set by the global flags
+func clientOptions() k8s.ClientOptions {
+	return k8s.ClientOptions{
+		KubeConfig:            kubeConfig,
+		Context:               kubeContext,
+		Cluster:               kubeCluster,
+		User:                  kubeUser,
+		Namespace:             kubeNamespace,
+		Server:                kubeServer,
+		InCluster:             inCluster,
+		InsecureSkipTLSVerify: insecureSkipTLSVerify,
+		CertificateAuthority:  certificateAuthority,
+		QPS:                   qps,
+		Burst:                 burst,
+		Timeout:               timeout,
+		Impersonate:           impersonate,
+		ImpersonateGroups:     impersonateGroups,
+		ImpersonateUID:        impersonateUID,
+	}
+}
+
 // Execute runs the k8sutil root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
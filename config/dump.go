@@ -1,28 +1,225 @@
 package config
 
-import "k8s.io/apimachinery/pkg/runtime/schema"
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
 
 // DumpCommand is the configuration for the dump subcommand
+// PageSize is the number of resources requested from the api server at a time
+// Clean removes server populated fields from every dumped resource so that it can be re-applied
+// Workers is the number of dumps listed concurrently
+// All, if set, adds a dump for every listable resource in the cluster
+// Redact redacts sensitive values from every dumped resource
 type DumpCommand struct {
-	Dumps []Dump
+	PageSize int64
+	Clean    bool
+	Workers  int
+	All      *DumpAll
+	Redact   Redaction
+	Dumps    []Dump
 }
 
-// Dump defines the configuration fields for a single gvr dump
-// GVR represents a k8s GroupVersionResource
-type Dump struct {
-	GVR       schema.GroupVersionResource
+// Redaction modes
+const (
+	RedactPlaceholder = "placeholder"
+	RedactHash        = "hash"
+)
+
+// Redaction presets
+const (
+	// PresetSecrets redacts the data and stringData of Secrets, and their last applied configuration
+	PresetSecrets = "secrets"
+	// PresetCredentials redacts fields commonly holding credentials, e.g. password, token and privateKey, at any depth
+	PresetCredentials = "credentials"
+)
+
+// Redaction defines the configuration for redacting sensitive values
+// Mode is placeholder (the default) to replace values with a fixed placeholder, or hash to replace them with their
+// sha256 hash so that changes are still visible
+// Presets are built-in sets of fields to redact, Paths are paths to additional fields to redact,
+// the values of objects at a path are redacted individually
+type Redaction struct {
+	Mode    string
+	Presets []string
+	Paths   []string
+}
+
+// DumpAll defines the configuration for dumping every listable resource type
+// Namespace limits the dump to namespaced resources in that namespace
+// Include and Exclude name resources by plural, singular or short name, kind, or <resource>.<group>
+// Filters are applied to every resource
+type DumpAll struct {
 	Namespace string
+	Include   []string
+	Exclude   []string
 	Filters   Filter
 }
 
-// Filter defines the configuration for a dump filter
+// CheckKeys returns an error if more than one dump has the same key, dumps must be resolved first so that their GVRs are complete
+func (c DumpCommand) CheckKeys() error {
+	keys := make(map[string]int)
+	for i, dump := range c.Dumps {
+		key := dump.Key()
+		if j, ok := keys[key]; ok {
+			return fmt.Errorf("dumps %v and %v have the same key: %q, set a unique name on one of them", j, i, key)
+		}
+		keys[key] = i
+	}
+	return nil
+}
+
+// Dump defines the configuration fields for a single gvr dump
+// Name optionally identifies the dump in output, see Key
+// GVR represents a k8s GroupVersionResource, its resource may be a short name and its group and version may be omitted
+// Kind may be set instead of GVR.Resource to select the resource by kind
+// Namespace, Namespaces and the namespaces matching the NamespaceSelector label selector are combined,
+// the resource is listed from all namespaces if none are set
+// LabelSelector and FieldSelector are evaluated by the api server, Filters are applied to what it returns
+// Fields are paths to the only fields kept in output (along with apiVersion, kind, name and namespace), Omit are paths removed from output
+// Redact are paths to fields redacted in addition to those of DumpCommand.Redact
+// Columns are printed by the table and csv output formats
+// ReportMatches adds the array elements that satisfied filters with any or all match semantics to each resource under _matched
+type Dump struct {
+	Name              string
+	GVR               schema.GroupVersionResource
+	Kind              string
+	Namespace         string
+	Namespaces        []string
+	NamespaceSelector string
+	LabelSelector     string
+	FieldSelector     string
+	Filters           Filter
+	Fields            []string
+	Omit              []string
+	Redact            []string
+	Columns           []Column
+	ReportMatches     bool
+}
+
+// Column defines a column of table and csv output
+// Its value is either the value at the gjson Path, with arrays joined by commas, or the result of the Go Template
+// executed with the resource
+type Column struct {
+	Name     string
+	Path     string
+	Template string
+}
+
+// Key returns the key identifying the dump in output, its name if set or <group>/<version>/<resource>[/<namespaces>]
+// where namespaces is the comma separated list of Namespace, Namespaces and the NamespaceSelector in braces
+func (d Dump) Key() string {
+	if d.Name != "" {
+		return d.Name
+	}
+
+	var parts []string
+	if d.GVR.Group != "" {
+		parts = append(parts, d.GVR.Group)
+	}
+	parts = append(parts, d.GVR.Version, d.GVR.Resource)
+
+	namespaces := d.Namespaces
+	if d.Namespace != "" {
+		namespaces = append([]string{d.Namespace}, namespaces...)
+	}
+	if d.NamespaceSelector != "" {
+		namespaces = append(namespaces, "{"+d.NamespaceSelector+"}")
+	}
+	if len(namespaces) > 0 {
+		parts = append(parts, strings.Join(namespaces, ","))
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// Filter defines the configuration for a dump filter, filters form a boolean tree
+// A filter is satisfied when every part of it that is set is satisfied:
+// the embedded element, all Ands, at least one Or, all of All, at least one of Any and not Not
 type Filter struct {
+	FilterElement `mapstructure:",squash"`
+
 	Ands []FilterElement
 	Ors  []FilterElement
+	All  []Filter
+	Any  []Filter
+	Not  *Filter
 }
 
+// Filter operators, an empty Op is treated as OpEq
+const (
+	OpEq        = "eq"
+	OpNe        = "ne"
+	OpRegex     = "regex"
+	OpPrefix    = "prefix"
+	OpSuffix    = "suffix"
+	OpExists    = "exists"
+	OpNotExists = "notexists"
+	OpGt        = "gt"
+	OpGe        = "ge"
+	OpLt        = "lt"
+	OpLe        = "le"
+	OpIn        = "in"
+)
+
+// Array match semantics, when the value found at a filter element's Key is an array, MatchAny requires at least one of
+// its elements to satisfy the element's op and MatchAll requires every element to. Keys with a # array path default to
+// MatchAny, otherwise the array is compared as a whole.
+const (
+	MatchAny = "any"
+	MatchAll = "all"
+)
+
 // FilterElement is the key value to filter for
+// Op is the comparison applied to the value found at Key, Values and ValuesFrom are only used by OpIn
 type FilterElement struct {
-	Key   string
-	Value string
+	Key        string
+	Op         string
+	Value      string
+	Values     []string
+	ValuesFrom *ValuesFrom
+	Match      string
+}
+
+// ValuesFrom joins a dump to another dump's results, the value at Path in each resource of the dump keyed by Dump is
+// added to the element's Values, with arrays adding each of their elements. Dumps are listed in dependency order.
+type ValuesFrom struct {
+	Dump string
+	Path string
+}
+
+// Empty reports whether the filter has no conditions, an empty filter is satisfied by every resource
+func (f Filter) Empty() bool {
+	return f.Key == "" && len(f.Ands) == 0 && len(f.Ors) == 0 && len(f.All) == 0 && len(f.Any) == 0 && f.Not == nil
+}
+
+// References returns every ValuesFrom in the filter tree
+func (f Filter) References() []ValuesFrom {
+	var refs []ValuesFrom
+	add := func(e FilterElement) {
+		if e.ValuesFrom != nil {
+			refs = append(refs, *e.ValuesFrom)
+		}
+	}
+
+	add(f.FilterElement)
+	for _, e := range f.Ands {
+		add(e)
+	}
+	for _, e := range f.Ors {
+		add(e)
+	}
+	for _, g := range f.All {
+		refs = append(refs, g.References()...)
+	}
+	for _, g := range f.Any {
+		refs = append(refs, g.References()...)
+	}
+	if f.Not != nil {
+		refs = append(refs, f.Not.References()...)
+	}
+
+	return refs
 }
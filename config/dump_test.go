@@ -0,0 +1,110 @@
+package config
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDumpKey(t *testing.T) {
+	tests := []struct {
+		name string
+		dump Dump
+		want string
+	}{
+		{
+			name: "core group",
+			dump: Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+			want: "v1/pods",
+		},
+		{
+			name: "group",
+			dump: Dump{GVR: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+			want: "apps/v1/deployments",
+		},
+		{
+			name: "namespace",
+			dump: Dump{GVR: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, Namespace: "web"},
+			want: "apps/v1/deployments/web",
+		},
+		{
+			name: "namespaces",
+			dump: Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "web", Namespaces: []string{"db", "cache"}},
+			want: "v1/pods/web,db,cache",
+		},
+		{
+			name: "namespaces without namespace",
+			dump: Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespaces: []string{"db"}},
+			want: "v1/pods/db",
+		},
+		{
+			name: "namespace selector",
+			dump: Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, NamespaceSelector: "team=a,env!=dev"},
+			want: "v1/pods/{team=a,env!=dev}",
+		},
+		{
+			name: "namespaces and namespace selector",
+			dump: Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "web", NamespaceSelector: "team=a"},
+			want: "v1/pods/web,{team=a}",
+		},
+		{
+			name: "name",
+			dump: Dump{Name: "web-pods", GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "web"},
+			want: "web-pods",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dump.Key(); got != tt.want {
+				t.Errorf("Key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	tests := []struct {
+		name    string
+		dumps   []Dump
+		wantErr bool
+	}{
+		{
+			name:  "different namespaces",
+			dumps: []Dump{{GVR: pods, Namespace: "a"}, {GVR: pods, Namespace: "b"}, {GVR: pods}},
+		},
+		{
+			name:  "same resource in different groups",
+			dumps: []Dump{{GVR: schema.GroupVersionResource{Group: "a.io", Version: "v1", Resource: "pods"}}, {GVR: pods}},
+		},
+		{
+			name:  "named",
+			dumps: []Dump{{GVR: pods, LabelSelector: "app=a"}, {Name: "b", GVR: pods, LabelSelector: "app=b"}},
+		},
+		{
+			name:  "namespace selector",
+			dumps: []Dump{{GVR: pods, NamespaceSelector: "team=a"}, {GVR: pods}},
+		},
+		{
+			name:    "same key",
+			dumps:   []Dump{{GVR: pods, LabelSelector: "app=a"}, {GVR: pods, LabelSelector: "app=b"}},
+			wantErr: true,
+		},
+		{
+			name:    "name matching a key",
+			dumps:   []Dump{{Name: "v1/pods", GVR: schema.GroupVersionResource{Version: "v1", Resource: "secrets"}}, {GVR: pods}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DumpCommand{Dumps: tt.dumps}.CheckKeys()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
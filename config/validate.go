@@ -0,0 +1,344 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var (
+	kindRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+	ops = map[string]bool{
+		OpEq: true, OpNe: true, OpRegex: true, OpPrefix: true, OpSuffix: true, OpExists: true, OpNotExists: true,
+		OpGt: true, OpGe: true, OpLt: true, OpLe: true, OpIn: true,
+	}
+)
+
+// Validate checks the configuration without contacting a cluster and returns every problem found
+func (c DumpCommand) Validate() error {
+	var errs []error
+	add := func(prefix string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", prefix, err))
+		}
+	}
+
+	if c.PageSize < 0 {
+		add("pageSize", fmt.Errorf("must not be negative"))
+	}
+
+	if c.Workers < 0 {
+		add("workers", fmt.Errorf("must not be negative"))
+	}
+
+	add("redact", validateRedaction(c.Redact))
+
+	if c.All != nil {
+		add("all.filters", validateFilter(c.All.Filters))
+	}
+
+	for i, d := range c.Dumps {
+		prefix := fmt.Sprintf("dumps[%v]", i)
+		if d.Name != "" {
+			prefix = fmt.Sprintf("%v (%v)", prefix, d.Name)
+		}
+
+		for _, err := range validateDump(d) {
+			add(prefix, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func validateDump(d Dump) []error {
+	var errs []error
+	add := func(prefix string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", prefix, err))
+		}
+	}
+
+	if d.GVR.Resource == "" && d.Kind == "" {
+		errs = append(errs, fmt.Errorf("one of kind or gvr.resource must be set"))
+	}
+
+	if d.GVR.Group != "" {
+		add("gvr.group", joinErrs(validation.IsDNS1123Subdomain(d.GVR.Group)))
+	}
+
+	if d.GVR.Version != "" {
+		add("gvr.version", joinErrs(validation.IsDNS1035Label(d.GVR.Version)))
+	}
+
+	if d.GVR.Resource != "" {
+		// the resource may also be given as a kind so case is ignored
+		add("gvr.resource", joinErrs(validation.IsDNS1123Subdomain(strings.ToLower(d.GVR.Resource))))
+	}
+
+	if d.Kind != "" && !kindRegex.MatchString(d.Kind) {
+		add("kind", fmt.Errorf("%q is not a valid kind", d.Kind))
+	}
+
+	if d.Namespace != "" {
+		add("namespace", joinErrs(validation.IsDNS1123Label(d.Namespace)))
+	}
+
+	for _, ns := range d.Namespaces {
+		add("namespaces", joinErrs(validation.IsDNS1123Label(ns)))
+	}
+
+	if d.NamespaceSelector != "" {
+		_, err := labels.Parse(d.NamespaceSelector)
+		add("namespaceSelector", err)
+	}
+
+	if d.LabelSelector != "" {
+		_, err := labels.Parse(d.LabelSelector)
+		add("labelSelector", err)
+	}
+
+	if d.FieldSelector != "" {
+		_, err := fields.ParseSelector(d.FieldSelector)
+		add("fieldSelector", err)
+	}
+
+	add("filters", validateFilter(d.Filters))
+
+	for _, p := range d.Fields {
+		add("fields", validateObjectPath(p))
+	}
+
+	for _, p := range d.Omit {
+		add("omit", validateObjectPath(p))
+	}
+
+	for _, p := range d.Redact {
+		add("redact", validateObjectPath(p))
+	}
+
+	for i, c := range d.Columns {
+		add(fmt.Sprintf("columns[%v]", i), validateColumn(c))
+	}
+
+	return errs
+}
+
+func validateRedaction(r Redaction) error {
+	switch strings.ToLower(r.Mode) {
+	case "", RedactPlaceholder, RedactHash:
+	default:
+		return fmt.Errorf("unknown mode: %q, must be %v or %v", r.Mode, RedactPlaceholder, RedactHash)
+	}
+
+	for _, p := range r.Presets {
+		switch strings.ToLower(p) {
+		case PresetSecrets, PresetCredentials:
+		default:
+			return fmt.Errorf("unknown preset: %q, must be %v or %v", p, PresetSecrets, PresetCredentials)
+		}
+	}
+
+	for _, p := range r.Paths {
+		err := validateObjectPath(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateFilter(f Filter) error {
+	if f.Key != "" || f.Op != "" || f.Value != "" || len(f.Values) > 0 || f.ValuesFrom != nil || f.Match != "" {
+		err := validateElement(f.FilterElement)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, e := range f.Ands {
+		err := validateElement(e)
+		if err != nil {
+			return fmt.Errorf("ands[%v]: %v", i, err)
+		}
+	}
+
+	for i, e := range f.Ors {
+		err := validateElement(e)
+		if err != nil {
+			return fmt.Errorf("ors[%v]: %v", i, err)
+		}
+	}
+
+	for i, g := range f.All {
+		err := validateFilter(g)
+		if err != nil {
+			return fmt.Errorf("all[%v]: %v", i, err)
+		}
+	}
+
+	for i, g := range f.Any {
+		err := validateFilter(g)
+		if err != nil {
+			return fmt.Errorf("any[%v]: %v", i, err)
+		}
+	}
+
+	if f.Not != nil {
+		err := validateFilter(*f.Not)
+		if err != nil {
+			return fmt.Errorf("not: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func validateElement(e FilterElement) error {
+	err := validatePath(e.Key)
+	if err != nil {
+		return fmt.Errorf("key: %v", err)
+	}
+
+	op := strings.ToLower(e.Op)
+	if op == "" {
+		op = OpEq
+	}
+	if !ops[op] {
+		return fmt.Errorf("unknown op: %q", e.Op)
+	}
+
+	switch op {
+	case OpRegex:
+		_, err := regexp.Compile(e.Value)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+	case OpGt, OpGe, OpLt, OpLe:
+		_, numErr := strconv.ParseFloat(e.Value, 64)
+		_, timeErr := time.Parse(time.RFC3339, e.Value)
+		if numErr != nil && timeErr != nil {
+			return fmt.Errorf("value %q is neither a number nor an RFC3339 timestamp", e.Value)
+		}
+	case OpIn:
+		if len(e.Values) == 0 && e.ValuesFrom == nil {
+			return fmt.Errorf("op %v requires values or valuesFrom", op)
+		}
+	}
+
+	switch strings.ToLower(e.Match) {
+	case "", MatchAny, MatchAll:
+	default:
+		return fmt.Errorf("unknown match: %q, must be %v or %v", e.Match, MatchAny, MatchAll)
+	}
+
+	if e.ValuesFrom != nil {
+		if op != OpIn {
+			return fmt.Errorf("valuesFrom requires op %v", OpIn)
+		}
+		if e.ValuesFrom.Dump == "" {
+			return fmt.Errorf("valuesFrom.dump must be set")
+		}
+		err := validatePath(e.ValuesFrom.Path)
+		if err != nil {
+			return fmt.Errorf("valuesFrom.path: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// validatePath checks the syntax of a gjson path
+func validatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("path must not be empty")
+	}
+
+	var stack []byte
+	closers := map[byte]byte{')': '(', ']': '[', '}': '{'}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch c {
+		case '\\':
+			i++
+		case '(', '[', '{':
+			stack = append(stack, c)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closers[c] {
+				return fmt.Errorf("path %q has unbalanced %q", path, c)
+			}
+			stack = stack[:len(stack)-1]
+		case '.':
+			if len(stack) == 0 && (i == 0 || i == len(path)-1 || path[i+1] == '.') {
+				return fmt.Errorf("path %q has an empty key", path)
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("path %q has unbalanced %q", path, stack[len(stack)-1])
+	}
+
+	return nil
+}
+
+// validateObjectPath checks that path is a gjson path made up of object keys and array indexes only, where '#' selects
+// every element of an array, without wildcards, queries or modifiers
+func validateObjectPath(path string) error {
+	err := validatePath(path)
+	if err != nil {
+		return err
+	}
+
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '.':
+			start = i + 1
+		case '#':
+			// '#' is only supported as a whole key
+			if i == start && (i+1 == len(path) || path[i+1] == '.') {
+				continue
+			}
+			fallthrough
+		case '*', '?', '|', '@':
+			return fmt.Errorf("path %q must only contain object keys, array indexes and #, %q is not supported", path, path[i])
+		}
+	}
+
+	return nil
+}
+
+func validateColumn(c Column) error {
+	if c.Path == "" && c.Template == "" {
+		return fmt.Errorf("one of path or template must be set")
+	}
+
+	if c.Path != "" {
+		return validatePath(c.Path)
+	}
+
+	// functions are only checked by name when parsing, their implementations are provided when output is printed
+	stub := func(...interface{}) string { return "" }
+	_, err := template.New(c.Name).Funcs(template.FuncMap{"join": stub, "gjson": stub}).Parse(c.Template)
+	return err
+}
+
+func joinErrs(msgs []string) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%v", strings.Join(msgs, ", "))
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"metadata.name", false},
+		{`metadata.labels.app\.kubernetes\.io/name`, false},
+		{`subjects.#(kind=="User").name`, false},
+		{`subjects.#(name%"u-*")#.name`, false},
+		{"spec.containers.0.image", false},
+		{"metadata.annotations|@keys", false},
+		{`data.a\.b`, false},
+		{"", true},
+		{".metadata", true},
+		{"metadata.", true},
+		{"metadata..name", true},
+		{`subjects.#(kind=="User".name`, true},
+		{`subjects.#(kind=="User"]`, true},
+		{"spec)", true},
+		{`subjects.#(name=="a.")`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := validatePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateObjectPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"metadata.name", false},
+		{`metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`, false},
+		{"spec.containers.0.image", false},
+		{"spec.containers.#.env", false},
+		{"spec.containers.#", false},
+		{"#.name", false},
+		{`data.a\#b`, false},
+		{`data.a\*b`, false},
+		{"spec.containers.#.env.#.value", false},
+		{"", true},
+		{"metadata..name", true},
+		{"spec.containers.#0", true},
+		{"spec.containers.0#", true},
+		{"spec.containers.##", true},
+		{`spec.containers.#(name=="app").image`, true},
+		{"metadata.labels.app*", true},
+		{"metadata.na?e", true},
+		{"metadata.annotations|@keys", true},
+		{"@this", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := validateObjectPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateObjectPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	tests := []struct {
+		name    string
+		cfg     DumpCommand
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg: DumpCommand{Dumps: []Dump{{
+				GVR:     pods,
+				Filters: Filter{FilterElement: FilterElement{Key: "metadata.name", Op: OpPrefix, Value: "web-"}},
+				Fields:  []string{"metadata.name", "spec.containers.#.image"},
+			}}},
+		},
+		{
+			name: "kind only",
+			cfg:  DumpCommand{Dumps: []Dump{{Kind: "Pod"}}},
+		},
+		{
+			name:    "no resource",
+			cfg:     DumpCommand{Dumps: []Dump{{Namespace: "default"}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative page size",
+			cfg:     DumpCommand{PageSize: -1},
+			wantErr: true,
+		},
+		{
+			name:    "unknown redaction mode",
+			cfg:     DumpCommand{Redact: Redaction{Mode: "shred"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown op",
+			cfg:     DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{FilterElement: FilterElement{Key: "metadata.name", Op: "like"}}}}},
+			wantErr: true,
+		},
+		{
+			name: "invalid nested filter",
+			cfg: DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{Any: []Filter{{
+				Not: &Filter{Ands: []FilterElement{{Key: "spec.replicas", Op: OpGt, Value: "many"}}},
+			}}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "in without values",
+			cfg:     DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{FilterElement: FilterElement{Key: "metadata.name", Op: OpIn}}}}},
+			wantErr: true,
+		},
+		{
+			name: "values from",
+			cfg: DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{FilterElement: FilterElement{
+				Key: "spec.serviceAccountName", Op: OpIn, ValuesFrom: &ValuesFrom{Dump: "sas", Path: "metadata.name"},
+			}}}}},
+		},
+		{
+			name: "values from without in",
+			cfg: DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{FilterElement: FilterElement{
+				Key: "spec.serviceAccountName", ValuesFrom: &ValuesFrom{Dump: "sas", Path: "metadata.name"},
+			}}}}},
+			wantErr: true,
+		},
+		{
+			name: "values from without dump",
+			cfg: DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{FilterElement: FilterElement{
+				Key: "spec.serviceAccountName", Op: OpIn, ValuesFrom: &ValuesFrom{Path: "metadata.name"},
+			}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown match",
+			cfg:     DumpCommand{Dumps: []Dump{{GVR: pods, Filters: Filter{FilterElement: FilterElement{Key: "spec.ports.#.port", Match: "some"}}}}},
+			wantErr: true,
+		},
+		{
+			name:    "query in fields",
+			cfg:     DumpCommand{Dumps: []Dump{{GVR: pods, Fields: []string{`spec.containers.#(name=="app")`}}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid label selector",
+			cfg:     DumpCommand{Dumps: []Dump{{GVR: pods, LabelSelector: "app in (web"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
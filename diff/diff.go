@@ -0,0 +1,254 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// VolatilePaths are fields that change without a change to the resource's configuration, they're ignored by default
+var VolatilePaths = []string{
+	"metadata.uid",
+	"metadata.resourceVersion",
+	"metadata.generation",
+	"metadata.managedFields",
+	"metadata.selfLink",
+	"metadata.creationTimestamp",
+}
+
+// Result is the difference between two sets of resources
+type Result struct {
+	Added   []Object `json:"added"`
+	Removed []Object `json:"removed"`
+	Changed []Object `json:"changed"`
+}
+
+// Empty reports whether there are no differences
+func (r Result) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Object identifies a resource that was added, removed or changed, Fields is only set for changed resources
+type Object struct {
+	ID     string  `json:"id"`
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Field is a changed field, Old or New is nil when the field was added or removed
+type Field struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// ID returns the identity resources are matched by, <kind>[.<group>] [<namespace>/]<name>.
+// The version is excluded so that resources are matched across versions of their group.
+func ID(item unstructured.Unstructured) string {
+	gvk := item.GroupVersionKind()
+
+	kind := gvk.Kind
+	if gvk.Group != "" {
+		kind = strings.Join([]string{gvk.Kind, gvk.Group}, ".")
+	}
+
+	name := item.GetName()
+	if ns := item.GetNamespace(); ns != "" {
+		name = strings.Join([]string{ns, name}, "/")
+	}
+
+	return kind + " " + name
+}
+
+// Resources compares old and new resources, matched by ID, ignoring fields at the ignore paths and their children
+func Resources(old, new []unstructured.Unstructured, ignore []string) (Result, error) {
+	oldIndex, err := index(old)
+	if err != nil {
+		return Result{}, err
+	}
+
+	newIndex, err := index(new)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var res Result
+	for id, o := range oldIndex {
+		n, ok := newIndex[id]
+		if !ok {
+			res.Removed = append(res.Removed, Object{ID: id})
+			continue
+		}
+
+		var fields []Field
+		compare("", o, n, ignore, &fields)
+		if len(fields) > 0 {
+			res.Changed = append(res.Changed, Object{ID: id, Fields: fields})
+		}
+	}
+
+	for id := range newIndex {
+		if _, ok := oldIndex[id]; !ok {
+			res.Added = append(res.Added, Object{ID: id})
+		}
+	}
+
+	for _, objs := range [][]Object{res.Added, res.Removed, res.Changed} {
+		sort.Slice(objs, func(i, j int) bool { return objs[i].ID < objs[j].ID })
+	}
+
+	return res, nil
+}
+
+// index normalizes resources to plain json values keyed by ID, so that values decoded differently
+// (e.g. int64 from the api server and float64 from a file) compare equal
+func index(items []unstructured.Unstructured) (map[string]interface{}, error) {
+	ind := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		raw, err := json.Marshal(item.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		var v interface{}
+		err = json.Unmarshal(raw, &v)
+		if err != nil {
+			return nil, err
+		}
+
+		ind[ID(item)] = v
+	}
+	return ind, nil
+}
+
+// compare appends the differences between o and n at path to fields
+func compare(path string, o, n interface{}, ignore []string, fields *[]Field) {
+	if ignored(path, ignore) {
+		return
+	}
+
+	switch ov := o.(type) {
+	case map[string]interface{}:
+		nv, ok := n.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make(map[string]bool)
+		for k := range ov {
+			keys[k] = true
+		}
+		for k := range nv {
+			keys[k] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			compare(join(path, escape(k)), ov[k], nv[k], ignore, fields)
+		}
+		return
+	case []interface{}:
+		nv, ok := n.([]interface{})
+		if !ok {
+			break
+		}
+
+		l := len(ov)
+		if len(nv) > l {
+			l = len(nv)
+		}
+
+		for i := 0; i < l; i++ {
+			var oe, ne interface{}
+			if i < len(ov) {
+				oe = ov[i]
+			}
+			if i < len(nv) {
+				ne = nv[i]
+			}
+			compare(join(path, strconv.Itoa(i)), oe, ne, ignore, fields)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(o, n) {
+		*fields = append(*fields, Field{Path: path, Old: o, New: n})
+	}
+}
+
+func ignored(path string, ignore []string) bool {
+	for _, ig := range ignore {
+		if path == ig || strings.HasPrefix(path, ig+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// escape escapes dots in a key the same way as gjson paths
+func escape(key string) string {
+	return strings.ReplaceAll(key, ".", `\.`)
+}
+
+// WriteText writes the differences to w, one resource per line prefixed with +, - or ~ for added, removed or changed
+// resources, followed by one line per changed field
+func (r Result) WriteText(w io.Writer) error {
+	for _, o := range r.Added {
+		_, err := fmt.Fprintf(w, "+ %s\n", o.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, o := range r.Removed {
+		_, err := fmt.Fprintf(w, "- %s\n", o.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, o := range r.Changed {
+		_, err := fmt.Fprintf(w, "~ %s\n", o.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range o.Fields {
+			_, err = fmt.Fprintf(w, "    %s: %s -> %s\n", f.Path, formatValue(f.Old), formatValue(f.New))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func items(t *testing.T, docs ...string) []unstructured.Unstructured {
+	var l []unstructured.Unstructured
+	for _, d := range docs {
+		var obj map[string]interface{}
+		err := json.Unmarshal([]byte(d), &obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l = append(l, unstructured.Unstructured{Object: obj})
+	}
+	return l
+}
+
+func TestID(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-0", "namespace": "web"}}`, "Pod web/web-0"},
+		{`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "web"}}`, "Namespace web"},
+		{`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web", "namespace": "web"}}`, "Deployment.apps web/web"},
+		{`{"apiVersion": "apps/v1beta2", "kind": "Deployment", "metadata": {"name": "web", "namespace": "web"}}`, "Deployment.apps web/web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := ID(items(t, tt.doc)[0]); got != tt.want {
+				t.Errorf("ID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResources(t *testing.T) {
+	const (
+		web  = `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web", "namespace": "a", "resourceVersion": "1"}, "data": {"a": "1", "b": "2"}}`
+		db   = `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "db", "namespace": "a"}, "data": {"a": "1"}}`
+		node = `{"apiVersion": "v1", "kind": "Node", "metadata": {"name": "n1"}}`
+	)
+
+	tests := []struct {
+		name   string
+		old    []string
+		new    []string
+		ignore []string
+		want   Result
+	}{
+		{
+			name: "equal",
+			old:  []string{web, db},
+			new:  []string{db, web},
+		},
+		{
+			name: "added and removed",
+			old:  []string{web, node},
+			new:  []string{db, web},
+			want: Result{Added: []Object{{ID: "ConfigMap a/db"}}, Removed: []Object{{ID: "Node n1"}}},
+		},
+		{
+			name: "changed fields",
+			old:  []string{web},
+			new: []string{`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web", "namespace": "a", "resourceVersion": "2"},
+				"data": {"a": "3", "c": "4", "d.e": "5"}}`},
+			ignore: VolatilePaths,
+			want: Result{Changed: []Object{{ID: "ConfigMap a/web", Fields: []Field{
+				{Path: "data.a", Old: "1", New: "3"},
+				{Path: "data.b", Old: "2"},
+				{Path: "data.c", New: "4"},
+				{Path: `data.d\.e`, New: "5"},
+			}}}},
+		},
+		{
+			name: "ignored fields and their children",
+			old:  []string{web},
+			new: []string{`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web", "namespace": "a", "resourceVersion": "2"},
+				"data": {"a": "3", "b": "2"}}`},
+			ignore: []string{"metadata.resourceVersion", "data"},
+		},
+		{
+			name: "arrays",
+			old:  []string{`{"kind": "Pod", "metadata": {"name": "p"}, "spec": {"args": ["a", "b"], "replicas": 1}}`},
+			new:  []string{`{"kind": "Pod", "metadata": {"name": "p"}, "spec": {"args": ["a", "c", "d"], "replicas": 1.0}}`},
+			want: Result{Changed: []Object{{ID: "Pod p", Fields: []Field{
+				{Path: "spec.args.1", Old: "b", New: "c"},
+				{Path: "spec.args.2", New: "d"},
+			}}}},
+		},
+		{
+			name: "type change",
+			old:  []string{`{"kind": "Pod", "metadata": {"name": "p"}, "spec": {"args": ["a"]}}`},
+			new:  []string{`{"kind": "Pod", "metadata": {"name": "p"}, "spec": {"args": "a"}}`},
+			want: Result{Changed: []Object{{ID: "Pod p", Fields: []Field{
+				{Path: "spec.args", Old: []interface{}{"a"}, New: "a"},
+			}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resources(items(t, tt.old...), items(t, tt.new...), tt.ignore)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resources() = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != reflect.DeepEqual(tt.want, Result{}) {
+				t.Errorf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
+
+func TestResourcesNumbers(t *testing.T) {
+	// resources from the api server have int64 numbers, ones loaded from a file have float64 numbers
+	old := []unstructured.Unstructured{{Object: map[string]interface{}{
+		"kind": "Pod", "metadata": map[string]interface{}{"name": "p"}, "spec": map[string]interface{}{"replicas": int64(3)},
+	}}}
+	res, err := Resources(old, items(t, `{"kind": "Pod", "metadata": {"name": "p"}, "spec": {"replicas": 3}}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Empty() {
+		t.Errorf("Resources() = %+v, want no differences", res)
+	}
+}
@@ -1,67 +1,405 @@
 package k8s
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 const (
-	clientTimeout = 30 * time.Second
+	// DefaultQPS and DefaultBurst limit the rate of requests to the api server when ClientOptions doesn't set them
+	DefaultQPS   = 50
+	DefaultBurst = 100
+	// DefaultTimeout is the suggested timeout of requests to the api server
+	DefaultTimeout = 30 * time.Second
+
+	// InClusterContext is reported as the context used with in-cluster configuration
+	InClusterContext = "in-cluster"
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 )
 
-// GetClient returns a dynamic client to cluster defined by kubeconfig for the GVR passed in.
-func GetClient(kubeConfig string) (*kubernetes.Clientset, error) {
-	config, err := getConfig(kubeConfig)
+// ClientOptions define how clients connect to the cluster, they mirror kubectl's global flags
+type ClientOptions struct {
+	// KubeConfig is the path to the kubeconfig file, when empty the files in $KUBECONFIG or ~/.kube/config are used
+	KubeConfig string
+	// Context overrides the kubeconfig's current context
+	Context string
+	// Cluster and User override the cluster and user of the context
+	Cluster string
+	User    string
+	// Namespace overrides the namespace of the context
+	Namespace string
+	// Server overrides the address of the api server
+	Server string
+	// InCluster uses the service account of the pod the command runs in rather than a kubeconfig, it's also used when
+	// running in a pod without a kubeconfig
+	InCluster bool
+	// InsecureSkipTLSVerify disables verification of the api server's certificate
+	InsecureSkipTLSVerify bool
+	// CertificateAuthority is the path to a file with the CA certificates used to verify the api server's certificate,
+	// it replaces the CA in the kubeconfig
+	CertificateAuthority string
+	// QPS and Burst limit the rate of requests to the api server, DefaultQPS and DefaultBurst are used when they're 0
+	// and a negative QPS disables rate limiting
+	QPS   float32
+	Burst int
+	// Timeout is the timeout of each request to the api server except watches, 0 means no timeout
+	Timeout time.Duration
+	// Impersonate, ImpersonateGroups and ImpersonateUID make requests as another user, replacing any impersonation
+	// in the kubeconfig. Groups and UID can only be set along with the user.
+	Impersonate       string
+	ImpersonateGroups []string
+	ImpersonateUID    string
+}
+
+// Factory creates clients for the cluster defined by its options. The rest.Config and clients are created once and
+// shared, so every request made through a Factory is subject to the same rate limit.
+type Factory struct {
+	opts ClientOptions
+
+	mtx       sync.Mutex
+	config    *rest.Config
+	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
+	watch     dynamic.Interface
+	namespace string
+}
+
+// NewFactory returns a Factory for the cluster defined by opts, nothing is loaded until a client is requested
+func NewFactory(opts ClientOptions) *Factory {
+	return &Factory{opts: opts}
+}
+
+// Config returns the rest.Config shared by the factory's clients
+func (f *Factory) Config() (*rest.Config, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.loadConfig()
+}
+
+func (f *Factory) loadConfig() (*rest.Config, error) {
+	if f.config != nil {
+		return f.config, nil
+	}
+
+	config, err := getClientConfig(f.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	f.config = config
+	return config, nil
+}
+
+// Client returns a typed client for the cluster.
+func (f *Factory) Client() (*kubernetes.Clientset, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.clientset != nil {
+		return f.clientset, nil
+	}
+
+	config, err := f.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	f.clientset, err = kubernetes.NewForConfig(config)
+	return f.clientset, err
+}
+
+// DynamicClient returns a dynamic client for the cluster.
+func (f *Factory) DynamicClient() (dynamic.Interface, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.dynamic != nil {
+		return f.dynamic, nil
+	}
+
+	config, err := f.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	f.dynamic, err = dynamic.NewForConfig(config)
+	return f.dynamic, err
+}
+
+// WatchClient returns a dynamic client for watches. Unlike the other clients its requests have no timeout, since the
+// timeout would cut off every watch, but it shares their rate limiter.
+func (f *Factory) WatchClient() (dynamic.Interface, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.watch != nil {
+		return f.watch, nil
+	}
+
+	config, err := f.loadConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	// hack for: x509: certificate signed by unknown authority
-	config.TLSClientConfig.Insecure = true
-	config.TLSClientConfig.CAData = nil
+	config = rest.CopyConfig(config)
+	config.Timeout = 0
 
-	config.Timeout = clientTimeout
+	f.watch, err = dynamic.NewForConfig(config)
+	return f.watch, err
+}
 
-	clientset, err := kubernetes.NewForConfig(config)
+// ResourceClient returns a dynamic client for the resource of a RESTMapping, scoped to namespace if the resource is namespaced.
+func (f *Factory) ResourceClient(mapping *meta.RESTMapping, namespace string) (dynamic.ResourceInterface, error) {
+	cli, err := f.DynamicClient()
 	if err != nil {
 		return nil, err
 	}
 
-	return clientset, nil
+	return resourceClient(cli, mapping, namespace), nil
 }
 
-// GetDynamicClient returns a dynamic client to cluster defined by kubeconfig for the GVR passed in.
-func GetDynamicClient(kubeConfig string, gvr schema.GroupVersionResource) (dynamic.NamespaceableResourceInterface, error) {
-	config, err := getConfig(kubeConfig)
+// WatchResourceClient is like ResourceClient but uses the client returned by WatchClient.
+func (f *Factory) WatchResourceClient(mapping *meta.RESTMapping, namespace string) (dynamic.ResourceInterface, error) {
+	cli, err := f.WatchClient()
 	if err != nil {
 		return nil, err
 	}
 
-	// hack for: x509: certificate signed by unknown authority
-	config.TLSClientConfig.Insecure = true
-	config.TLSClientConfig.CAData = nil
+	return resourceClient(cli, mapping, namespace), nil
+}
+
+func resourceClient(cli dynamic.Interface, mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return cli.Resource(mapping.Resource)
+	}
 
-	config.Timeout = clientTimeout
+	return cli.Resource(mapping.Resource).Namespace(namespace)
+}
 
-	cli, err := dynamic.NewForConfig(config)
+// DiscoveryClient returns a discovery client for the cluster, unlike the other clients a new one is returned each time
+// so discovery isn't cached.
+func (f *Factory) DiscoveryClient() (*discovery.DiscoveryClient, error) {
+	config, err := f.Config()
 	if err != nil {
 		return nil, err
 	}
 
-	return cli.Resource(gvr), nil
+	return discovery.NewDiscoveryClientForConfig(config)
+}
+
+// CurrentContext returns the name of the kubeconfig context used.
+func (f *Factory) CurrentContext() (string, error) {
+	inCluster, err := useInCluster(f.opts)
+	if err != nil || inCluster {
+		return InClusterContext, err
+	}
+
+	if f.opts.Context != "" {
+		return f.opts.Context, nil
+	}
+
+	raw, err := clientConfig(f.opts).RawConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return raw.CurrentContext, nil
+}
+
+// Namespace returns the namespace set by the options, or the namespace of the kubeconfig context used, or default if
+// neither sets one. With in-cluster configuration, it's the namespace of the pod's service account unless the options
+// set one. It's used for namespaced resources that don't specify a namespace.
+func (f *Factory) Namespace() (string, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.namespace != "" {
+		return f.namespace, nil
+	}
+
+	inCluster, err := useInCluster(f.opts)
+	if err != nil {
+		return "", err
+	}
+
+	if inCluster && f.opts.Namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return "", err
+		}
+		f.namespace = strings.TrimSpace(string(ns))
+		return f.namespace, nil
+	}
+
+	f.namespace, _, err = clientConfig(f.opts).Namespace()
+	return f.namespace, err
+}
+
+// getClientConfig returns the rest.Config used by clients of the cluster.
+func getClientConfig(clientOpts ClientOptions) (*rest.Config, error) {
+	config, err := getConfig(clientOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = configureTLS(config, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = configureImpersonation(config, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Timeout = clientOpts.Timeout
+
+	// a single limiter is shared by every client created from config
+	qps, burst := clientOpts.QPS, clientOpts.Burst
+	if qps == 0 {
+		qps = DefaultQPS
+	}
+	if burst <= 0 {
+		burst = DefaultBurst
+	}
+	if qps < 0 {
+		config.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+	} else {
+		config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+
+	return config, nil
+}
+
+// configureImpersonation applies the impersonation options to config
+func configureImpersonation(config *rest.Config, clientOpts ClientOptions) error {
+	if clientOpts.Impersonate == "" {
+		if len(clientOpts.ImpersonateGroups) > 0 || clientOpts.ImpersonateUID != "" {
+			return fmt.Errorf("--as-group and --as-uid can only be used with --as")
+		}
+		return nil
+	}
+
+	logrus.Debugf("impersonating user %q, groups %v", clientOpts.Impersonate, clientOpts.ImpersonateGroups)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: clientOpts.Impersonate,
+		Groups:   clientOpts.ImpersonateGroups,
+		UID:      clientOpts.ImpersonateUID,
+	}
+
+	return nil
+}
+
+// configureTLS applies the TLS options to config, by default the api server's certificate is verified using the CA in the kubeconfig.
+func configureTLS(config *rest.Config, clientOpts ClientOptions) error {
+	if clientOpts.InsecureSkipTLSVerify && clientOpts.CertificateAuthority != "" {
+		return fmt.Errorf("only one of --insecure-skip-tls-verify and --certificate-authority can be set")
+	}
+
+	if clientOpts.CertificateAuthority != "" {
+		caFile, err := filepath.Abs(clientOpts.CertificateAuthority)
+		if err != nil {
+			return err
+		}
+
+		config.TLSClientConfig.CAFile = caFile
+		config.TLSClientConfig.CAData = nil
+		config.TLSClientConfig.Insecure = false
+	}
+
+	if clientOpts.InsecureSkipTLSVerify {
+		// client-go refuses to use a CA when verification is disabled
+		config.TLSClientConfig.Insecure = true
+		config.TLSClientConfig.CAFile = ""
+		config.TLSClientConfig.CAData = nil
+	}
+
+	if config.TLSClientConfig.Insecure {
+		logrus.Warnf("TLS certificate verification is disabled for %v, the connection is vulnerable to man-in-the-middle attacks", config.Host)
+	}
+
+	return nil
 }
 
 // getConfig returns the kubernetes rest.Config for the cluster.
-func getConfig(kubeConfig string) (*rest.Config, error) {
-	kubeConfig, err := filepath.Abs(kubeConfig)
+func getConfig(clientOpts ClientOptions) (*rest.Config, error) {
+	inCluster, err := useInCluster(clientOpts)
 	if err != nil {
 		return nil, err
 	}
-	return clientcmd.BuildConfigFromFlags("", kubeConfig)
+
+	if !inCluster {
+		return clientConfig(clientOpts).ClientConfig()
+	}
+
+	logrus.Debug("using in-cluster config")
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if clientOpts.Server != "" {
+		config.Host = clientOpts.Server
+	}
+
+	return config, nil
+}
+
+// useInCluster reports whether in-cluster configuration is used, either because it's requested or because the command
+// is running in a pod and no kubeconfig is set or found in ~/.kube/config.
+func useInCluster(clientOpts ClientOptions) (bool, error) {
+	if clientOpts.InCluster {
+		if clientOpts.KubeConfig != "" || clientOpts.Context != "" || clientOpts.Cluster != "" || clientOpts.User != "" {
+			return false, fmt.Errorf("--in-cluster can't be used with --kube-config, --context, --cluster or --user")
+		}
+		return true, nil
+	}
+
+	if clientOpts.KubeConfig != "" || os.Getenv(clientcmd.RecommendedConfigPathEnvVar) != "" {
+		return false, nil
+	}
+
+	if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+		return false, nil
+	}
+
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != "", nil
+}
+
+// clientConfig loads the kubeconfig like kubectl, merging the files in $KUBECONFIG unless one is set explicitly,
+// and applies the overrides in clientOpts.
+func clientConfig(clientOpts ClientOptions) clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = clientOpts.KubeConfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: clientOpts.Context,
+		Context: clientcmdapi.Context{
+			Cluster:   clientOpts.Cluster,
+			AuthInfo:  clientOpts.User,
+			Namespace: clientOpts.Namespace,
+		},
+		ClusterInfo: clientcmdapi.Cluster{
+			Server: clientOpts.Server,
+		},
+	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
 }
@@ -0,0 +1,324 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// testKubeConfig is a kubeconfig with a context for each of two clusters, the first of which is current
+const testKubeConfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+    certificate-authority-data: ZGV2LWNh
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+    insecure-skip-tls-verify: true
+users:
+- name: dev-user
+  user:
+    token: dev-token
+- name: prod-user
+  user:
+    token: prod-token
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev-user
+    namespace: web
+- name: prod
+  context:
+    cluster: prod
+    user: prod-user
+`
+
+func writeKubeConfig(t *testing.T) string {
+	file := filepath.Join(t.TempDir(), "config")
+	err := ioutil.WriteFile(file, []byte(testKubeConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestConfigureTLS(t *testing.T) {
+	ca, err := filepath.Abs("ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tls     rest.TLSClientConfig
+		opts    ClientOptions
+		want    rest.TLSClientConfig
+		wantErr bool
+	}{
+		{
+			name: "kubeconfig ca",
+			tls:  rest.TLSClientConfig{CAData: []byte("ca")},
+			want: rest.TLSClientConfig{CAData: []byte("ca")},
+		},
+		{
+			name: "certificate authority replaces the kubeconfig's",
+			tls:  rest.TLSClientConfig{CAData: []byte("ca"), Insecure: true},
+			opts: ClientOptions{CertificateAuthority: "ca.crt"},
+			want: rest.TLSClientConfig{CAFile: ca},
+		},
+		{
+			name: "insecure",
+			tls:  rest.TLSClientConfig{CAFile: "/ca.crt", CAData: []byte("ca")},
+			opts: ClientOptions{InsecureSkipTLSVerify: true},
+			want: rest.TLSClientConfig{Insecure: true},
+		},
+		{
+			name:    "insecure and certificate authority",
+			opts:    ClientOptions{InsecureSkipTLSVerify: true, CertificateAuthority: "ca.crt"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &rest.Config{TLSClientConfig: tt.tls}
+			err := configureTLS(config, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configureTLS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !tlsEqual(config.TLSClientConfig, tt.want) {
+				t.Errorf("configureTLS() = %+v, want %+v", config.TLSClientConfig, tt.want)
+			}
+		})
+	}
+}
+
+func tlsEqual(a, b rest.TLSClientConfig) bool {
+	return a.Insecure == b.Insecure && a.CAFile == b.CAFile && string(a.CAData) == string(b.CAData)
+}
+
+func TestGetClientConfigTLS(t *testing.T) {
+	kubeConfig := writeKubeConfig(t)
+
+	config, err := getClientConfig(ClientOptions{KubeConfig: kubeConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Insecure || string(config.CAData) != "dev-ca" {
+		t.Errorf("TLS config = %+v, want the kubeconfig's ca to be verified", config.TLSClientConfig)
+	}
+
+	config, err = getClientConfig(ClientOptions{KubeConfig: kubeConfig, InsecureSkipTLSVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Insecure || len(config.CAData) > 0 {
+		t.Errorf("TLS config = %+v, want verification disabled", config.TLSClientConfig)
+	}
+}
+
+func TestClientOverrides(t *testing.T) {
+	kubeConfig := writeKubeConfig(t)
+
+	tests := []struct {
+		name      string
+		opts      ClientOptions
+		host      string
+		token     string
+		context   string
+		namespace string
+	}{
+		{
+			name:      "current context",
+			opts:      ClientOptions{},
+			host:      "https://dev.example.com:6443",
+			token:     "dev-token",
+			context:   "dev",
+			namespace: "web",
+		},
+		{
+			name:      "context",
+			opts:      ClientOptions{Context: "prod"},
+			host:      "https://prod.example.com:6443",
+			token:     "prod-token",
+			context:   "prod",
+			namespace: "default",
+		},
+		{
+			name:      "cluster and user",
+			opts:      ClientOptions{Cluster: "prod", User: "prod-user"},
+			host:      "https://prod.example.com:6443",
+			token:     "prod-token",
+			context:   "dev",
+			namespace: "web",
+		},
+		{
+			name:      "namespace and server",
+			opts:      ClientOptions{Namespace: "db", Server: "https://127.0.0.1:6443"},
+			host:      "https://127.0.0.1:6443",
+			token:     "dev-token",
+			context:   "dev",
+			namespace: "db",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.KubeConfig = kubeConfig
+			f := NewFactory(tt.opts)
+
+			config, err := f.Config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Host != tt.host || config.BearerToken != tt.token {
+				t.Errorf("config host, token = %v, %v, want %v, %v", config.Host, config.BearerToken, tt.host, tt.token)
+			}
+
+			context, err := f.CurrentContext()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if context != tt.context {
+				t.Errorf("CurrentContext() = %v, want %v", context, tt.context)
+			}
+
+			ns, err := f.Namespace()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ns != tt.namespace {
+				t.Errorf("Namespace() = %v, want %v", ns, tt.namespace)
+			}
+		})
+	}
+
+	_, err := getClientConfig(ClientOptions{KubeConfig: kubeConfig, Context: "missing"})
+	if err == nil {
+		t.Error("expected an error for a missing context")
+	}
+}
+
+func TestUseInCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		want    bool
+		wantErr bool
+	}{
+		{name: "in cluster", opts: ClientOptions{InCluster: true}, want: true},
+		{name: "in cluster with a namespace and server", opts: ClientOptions{InCluster: true, Namespace: "web", Server: "https://10.0.0.1"}, want: true},
+		{name: "kubeconfig", opts: ClientOptions{KubeConfig: "config"}},
+		{name: "in cluster with a kubeconfig", opts: ClientOptions{InCluster: true, KubeConfig: "config"}, wantErr: true},
+		{name: "in cluster with a context", opts: ClientOptions{InCluster: true, Context: "dev"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := useInCluster(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("useInCluster() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("useInCluster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	context, err := NewFactory(ClientOptions{InCluster: true}).CurrentContext()
+	if err != nil || context != InClusterContext {
+		t.Errorf("CurrentContext() = %v, %v, want %v", context, err, InClusterContext)
+	}
+}
+
+func TestFactoryConfig(t *testing.T) {
+	kubeConfig := writeKubeConfig(t)
+
+	tests := []struct {
+		name      string
+		opts      ClientOptions
+		qps       float32
+		unlimited bool
+	}{
+		{name: "defaults", opts: ClientOptions{}, qps: DefaultQPS},
+		{name: "qps and burst", opts: ClientOptions{QPS: 5, Burst: 10, Timeout: time.Second}, qps: 5},
+		{name: "unlimited", opts: ClientOptions{QPS: -1}, unlimited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.KubeConfig = kubeConfig
+			f := NewFactory(tt.opts)
+
+			config, err := f.Config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Timeout != tt.opts.Timeout {
+				t.Errorf("timeout = %v, want %v", config.Timeout, tt.opts.Timeout)
+			}
+
+			if tt.unlimited {
+				if config.RateLimiter.QPS() != flowcontrol.NewFakeAlwaysRateLimiter().QPS() {
+					t.Errorf("rate limiter qps = %v, want no limit", config.RateLimiter.QPS())
+				}
+			} else if config.RateLimiter.QPS() != tt.qps {
+				t.Errorf("rate limiter qps = %v, want %v", config.RateLimiter.QPS(), tt.qps)
+			}
+
+			again, err := f.Config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != config {
+				t.Error("Config() returned a different config, clients wouldn't share its rate limiter")
+			}
+		})
+	}
+}
+
+func TestConfigureImpersonation(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		want    rest.ImpersonationConfig
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name: "user",
+			opts: ClientOptions{Impersonate: "jane"},
+			want: rest.ImpersonationConfig{UserName: "jane"},
+		},
+		{
+			name: "user, groups and uid",
+			opts: ClientOptions{Impersonate: "system:serviceaccount:web:deployer", ImpersonateGroups: []string{"a", "b"}, ImpersonateUID: "1234"},
+			want: rest.ImpersonationConfig{UserName: "system:serviceaccount:web:deployer", Groups: []string{"a", "b"}, UID: "1234"},
+		},
+		{name: "groups without user", opts: ClientOptions{ImpersonateGroups: []string{"a"}}, wantErr: true},
+		{name: "uid without user", opts: ClientOptions{ImpersonateUID: "1234"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &rest.Config{}
+			err := configureImpersonation(config, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configureImpersonation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(config.Impersonate, tt.want) {
+				t.Errorf("configureImpersonation() = %+v, want %+v", config.Impersonate, tt.want)
+			}
+		})
+	}
+}
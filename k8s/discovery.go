@@ -0,0 +1,235 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/restmapper"
+)
+
+// Resolver resolves dump targets to the resources served by a cluster
+type Resolver struct {
+	groups []*restmapper.APIGroupResources
+	mapper meta.RESTMapper
+}
+
+// NewResolver returns a Resolver for the resources discovered in the cluster factory connects to
+func NewResolver(factory *Factory) (*Resolver, error) {
+	dc, err := factory.DiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := restmapper.GetAPIGroupResources(dc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolver{
+		groups: groups,
+		mapper: restmapper.NewDiscoveryRESTMapper(groups),
+	}, nil
+}
+
+// Mapper returns a RESTMapper for the discovered resources
+func (r *Resolver) Mapper() meta.RESTMapper {
+	return r.mapper
+}
+
+// Resolve returns the mapping of the resource a dump targets. The resource is matched by the dump's kind if set,
+// otherwise by gvr.resource which may be a plural, singular or short name, or a kind.
+// When gvr.group is empty every group is searched and when gvr.version is empty the group's preferred version is used.
+func (r *Resolver) Resolve(dump config.Dump) (*meta.RESTMapping, error) {
+	target := dump.GVR.Resource
+	if dump.Kind != "" {
+		target = dump.Kind
+	}
+	if target == "" {
+		return nil, fmt.Errorf("dump %q must set kind or gvr.resource", dump.Name)
+	}
+
+	// exact matches take precedence over matches by singular name, short name or kind
+	var exact, other []*meta.RESTMapping
+	for _, g := range r.groups {
+		if dump.GVR.Group != "" && g.Group.Name != dump.GVR.Group {
+			continue
+		}
+
+		version := dump.GVR.Version
+		if version == "" {
+			version = g.Group.PreferredVersion.Version
+		}
+
+		for _, res := range g.VersionedResources[version] {
+			if strings.Contains(res.Name, "/") { // subresource
+				continue
+			}
+
+			m := newMapping(g.Group.Name, version, res)
+			switch {
+			case dump.Kind != "":
+				if strings.EqualFold(res.Kind, dump.Kind) {
+					exact = append(exact, m)
+				}
+			case res.Name == target:
+				exact = append(exact, m)
+			case res.SingularName == target || strings.EqualFold(res.Kind, target) || contains(res.ShortNames, target):
+				other = append(other, m)
+			}
+		}
+	}
+
+	candidates := exact
+	if len(candidates) == 0 {
+		candidates = other
+	}
+
+	// like kubectl, prefer the core group when a name is served by more than one group
+	if len(candidates) > 1 {
+		var core []*meta.RESTMapping
+		for _, m := range candidates {
+			if m.Resource.Group == "" {
+				core = append(core, m)
+			}
+		}
+		if len(core) == 1 {
+			candidates = core
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no resource found for %v", describeTarget(dump))
+	case 1:
+		return candidates[0], nil
+	default:
+		var names []string
+		for _, m := range candidates {
+			names = append(names, ResourceString(m.Resource))
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%v is ambiguous, set gvr.group and gvr.version to one of: %v", describeTarget(dump), strings.Join(names, ", "))
+	}
+}
+
+// Validate checks that every dump in cfg resolves to a single resource served by the cluster, that the dumps' keys are
+// still unique once resolved and that joins reference existing dumps without cycles
+func (r *Resolver) Validate(cfg config.DumpCommand) error {
+	var errs []error
+	resolved := config.DumpCommand{Dumps: make([]config.Dump, len(cfg.Dumps))}
+	for i, dump := range cfg.Dumps {
+		mapping, err := r.Resolve(dump)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dumps[%v]: %v", i, err))
+			continue
+		}
+
+		dump.GVR = mapping.Resource
+		resolved.Dumps[i] = dump
+	}
+
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	err := resolved.CheckKeys()
+	if err != nil {
+		return err
+	}
+
+	_, err = orderDumps(resolved.Dumps)
+	return err
+}
+
+// Listable returns mappings for the preferred version of every resource that supports list and satisfies all's
+// namespace, include and exclude lists. Only namespaced resources are returned when all.Namespace is set.
+func (r *Resolver) Listable(all config.DumpAll) []*meta.RESTMapping {
+	var mappings []*meta.RESTMapping
+	for _, g := range r.groups {
+		version := g.Group.PreferredVersion.Version
+		for _, res := range g.VersionedResources[version] {
+			if strings.Contains(res.Name, "/") || !contains(res.Verbs, "list") {
+				continue
+			}
+
+			if all.Namespace != "" && !res.Namespaced {
+				continue
+			}
+
+			if len(all.Include) > 0 && !matchesAny(res, g.Group.Name, all.Include) {
+				continue
+			}
+
+			if matchesAny(res, g.Group.Name, all.Exclude) {
+				continue
+			}
+
+			mappings = append(mappings, newMapping(g.Group.Name, version, res))
+		}
+	}
+	return mappings
+}
+
+// matchesAny reports whether res is named by any of names, either by its plural, singular or short name or its kind,
+// or by its plural name qualified with its group as <resource>.<group>
+func matchesAny(res metav1.APIResource, group string, names []string) bool {
+	for _, name := range names {
+		switch {
+		case res.Name == name, res.SingularName == name, strings.EqualFold(res.Kind, name), contains(res.ShortNames, name):
+			return true
+		case group != "" && res.Name+"."+group == name:
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceString formats gvr as <resource>.<version>.<group>, the inverse of schema.ParseResourceArg
+func ResourceString(gvr schema.GroupVersionResource) string {
+	return strings.TrimSuffix(strings.Join([]string{gvr.Resource, gvr.Version, gvr.Group}, "."), ".")
+}
+
+func newMapping(group, version string, res metav1.APIResource) *meta.RESTMapping {
+	scope := meta.RESTScopeRoot
+	if res.Namespaced {
+		scope = meta.RESTScopeNamespace
+	}
+
+	return &meta.RESTMapping{
+		Resource:         schema.GroupVersionResource{Group: group, Version: version, Resource: res.Name},
+		GroupVersionKind: schema.GroupVersionKind{Group: group, Version: version, Kind: res.Kind},
+		Scope:            scope,
+	}
+}
+
+func describeTarget(dump config.Dump) string {
+	var parts []string
+	if dump.Kind != "" {
+		parts = append(parts, "kind="+dump.Kind)
+	}
+	if dump.GVR.Resource != "" {
+		parts = append(parts, "resource="+dump.GVR.Resource)
+	}
+	if dump.GVR.Version != "" {
+		parts = append(parts, "version="+dump.GVR.Version)
+	}
+	if dump.GVR.Group != "" {
+		parts = append(parts, "group="+dump.GVR.Group)
+	}
+	return strings.Join(parts, " ")
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
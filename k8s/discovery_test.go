@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+)
+
+// testResolver returns a Resolver for a cluster serving core pods, events and nodes, apps deployments in two versions,
+// events in a second group, widgets of kind Pod and tokenreviews, which can't be listed
+func testResolver() *Resolver {
+	group := func(name string, preferred string, versions map[string][]metav1.APIResource) *restmapper.APIGroupResources {
+		g := &restmapper.APIGroupResources{
+			Group:              metav1.APIGroup{Name: name, PreferredVersion: metav1.GroupVersionForDiscovery{Version: preferred}},
+			VersionedResources: versions,
+		}
+		for v := range versions {
+			g.Group.Versions = append(g.Group.Versions, metav1.GroupVersionForDiscovery{Version: v})
+		}
+		return g
+	}
+
+	verbs := metav1.Verbs{"get", "list", "watch"}
+	pods := metav1.APIResource{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", ShortNames: []string{"po"}, Verbs: verbs}
+	podLogs := metav1.APIResource{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get"}}
+	events := metav1.APIResource{Name: "events", SingularName: "event", Namespaced: true, Kind: "Event", ShortNames: []string{"ev"}, Verbs: verbs}
+	deployments := metav1.APIResource{Name: "deployments", SingularName: "deployment", Namespaced: true, Kind: "Deployment", ShortNames: []string{"deploy"}, Verbs: verbs}
+	nodes := metav1.APIResource{Name: "nodes", SingularName: "node", Kind: "Node", ShortNames: []string{"no"}, Verbs: verbs}
+	widgets := metav1.APIResource{Name: "widgets", SingularName: "widget", Namespaced: true, Kind: "Pod", Verbs: verbs}
+	tokenReviews := metav1.APIResource{Name: "tokenreviews", Kind: "TokenReview", Verbs: metav1.Verbs{"create"}}
+
+	return &Resolver{groups: []*restmapper.APIGroupResources{
+		group("", "v1", map[string][]metav1.APIResource{"v1": {pods, podLogs, events, nodes}}),
+		group("apps", "v1", map[string][]metav1.APIResource{"v1": {deployments}, "v1beta2": {deployments}}),
+		group("events.k8s.io", "v1", map[string][]metav1.APIResource{"v1": {events}}),
+		group("widgets.example.com", "v1", map[string][]metav1.APIResource{"v1": {widgets}}),
+		group("authentication.k8s.io", "v1", map[string][]metav1.APIResource{"v1": {tokenReviews}}),
+	}}
+}
+
+func TestResolve(t *testing.T) {
+	gvr := func(group, version, resource string) schema.GroupVersionResource {
+		return schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+	}
+
+	tests := []struct {
+		name    string
+		dump    config.Dump
+		want    schema.GroupVersionResource
+		wantErr string
+	}{
+		{name: "resource", dump: config.Dump{GVR: gvr("", "", "deployments")}, want: gvr("apps", "v1", "deployments")},
+		{name: "singular name", dump: config.Dump{GVR: gvr("", "", "deployment")}, want: gvr("apps", "v1", "deployments")},
+		{name: "short name", dump: config.Dump{GVR: gvr("", "", "deploy")}, want: gvr("apps", "v1", "deployments")},
+		{name: "kind as resource", dump: config.Dump{GVR: gvr("", "", "Deployment")}, want: gvr("apps", "v1", "deployments")},
+		{name: "version", dump: config.Dump{GVR: gvr("apps", "v1beta2", "deployments")}, want: gvr("apps", "v1beta2", "deployments")},
+		{name: "cluster scoped", dump: config.Dump{GVR: gvr("", "", "no")}, want: gvr("", "v1", "nodes")},
+		{name: "core group preferred", dump: config.Dump{GVR: gvr("", "", "events")}, want: gvr("", "v1", "events")},
+		{name: "group", dump: config.Dump{GVR: gvr("events.k8s.io", "", "events")}, want: gvr("events.k8s.io", "v1", "events")},
+		{name: "exact name preferred to kind", dump: config.Dump{GVR: gvr("", "", "pods")}, want: gvr("", "v1", "pods")},
+		{name: "kind", dump: config.Dump{Kind: "deployment"}, want: gvr("apps", "v1", "deployments")},
+		{name: "kind in group", dump: config.Dump{Kind: "Pod", GVR: gvr("widgets.example.com", "", "")}, want: gvr("widgets.example.com", "v1", "widgets")},
+		{name: "kind served by two groups", dump: config.Dump{Kind: "Pod"}, want: gvr("", "v1", "pods")},
+		{name: "unknown resource", dump: config.Dump{GVR: gvr("", "", "gadgets")}, wantErr: "no resource found"},
+		{name: "unknown version", dump: config.Dump{GVR: gvr("apps", "v2", "deployments")}, wantErr: "no resource found"},
+		{name: "subresource", dump: config.Dump{GVR: gvr("", "", "pods/log")}, wantErr: "no resource found"},
+	}
+
+	r := testResolver()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := r.Resolve(tt.dump)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Resource != tt.want {
+				t.Errorf("Resolve() = %v, want %v", m.Resource, tt.want)
+			}
+		})
+	}
+}
+
+func TestListable(t *testing.T) {
+	tests := []struct {
+		name string
+		all  config.DumpAll
+		want []string
+	}{
+		{
+			name: "everything",
+			want: []string{"pods.v1", "events.v1", "nodes.v1", "deployments.v1.apps", "events.v1.events.k8s.io", "widgets.v1.widgets.example.com"},
+		},
+		{
+			name: "namespace",
+			all:  config.DumpAll{Namespace: "web"},
+			want: []string{"pods.v1", "events.v1", "deployments.v1.apps", "events.v1.events.k8s.io", "widgets.v1.widgets.example.com"},
+		},
+		{
+			name: "include",
+			all:  config.DumpAll{Include: []string{"deploy", "Node", "events.events.k8s.io"}},
+			want: []string{"nodes.v1", "deployments.v1.apps", "events.v1.events.k8s.io"},
+		},
+		{
+			name: "exclude",
+			all:  config.DumpAll{Exclude: []string{"events", "po"}},
+			want: []string{"nodes.v1", "deployments.v1.apps", "widgets.v1.widgets.example.com"},
+		},
+		{
+			name: "include and exclude",
+			all:  config.DumpAll{Include: []string{"events"}, Exclude: []string{"events.events.k8s.io"}},
+			want: []string{"events.v1"},
+		},
+	}
+
+	r := testResolver()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range r.Listable(tt.all) {
+				got = append(got, ResourceString(m.Resource))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Listable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceString(t *testing.T) {
+	tests := []struct {
+		gvr  schema.GroupVersionResource
+		want string
+	}{
+		{schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "pods.v1"},
+		{schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "deployments.v1.apps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := ResourceString(tt.gvr); got != tt.want {
+				t.Errorf("ResourceString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,80 +1,510 @@
 package k8s
 
 import (
+	"context"
 	"encoding/json"
-	"strings"
+	"sync"
 
 	"github.com/ryansann/k8sutil/config"
 	"github.com/sirupsen/logrus"
-	"github.com/tidwall/gjson"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 )
 
-// GetDumps returns a map of resource dumps that satisfy GVRs and filters, map is keyed by resource
-func GetDumps(kubeConfig string, cfg config.DumpCommand) (map[string]interface{}, error) {
-	dumps := make(map[string]interface{}, 0)
+const (
+	defaultPageSize = 500
+	// discoveredSuffix is appended to the key of a dump discovered by --all when a configured dump has the same key
+	discoveredSuffix = " (all)"
+	// MatchedField is the top level field the array elements that satisfied a dump's filters are reported in
+	MatchedField = "_matched"
+)
 
-	for _, dump := range cfg.Dumps {
-		cli, err := GetDynamicClient(kubeConfig, dump.GVR)
+// DumpWriter receives the items of each dump as pages are retrieved from the api server
+type DumpWriter interface {
+	// Begin is called once per dump before any of its items are written
+	Begin(key string, dump config.Dump) error
+	// Write is called for every item that satisfies the dump's filters
+	Write(key string, item unstructured.Unstructured) error
+}
+
+// GetDumps returns a map of resource dumps that satisfy GVRs and filters, map is keyed by dump key
+func GetDumps(factory *Factory, cfg config.DumpCommand) (map[string][]unstructured.Unstructured, error) {
+	dumps := make(collector)
+
+	err := StreamDumps(factory, cfg, dumps)
+	if err != nil {
+		return nil, err
+	}
+
+	return dumps, nil
+}
+
+// StreamDumps lists each dump's resources a page at a time and writes the items satisfying its filters to w.
+// Dumps are listed one at a time unless cfg.Workers is greater than 1, in which case they're listed concurrently and
+// each dump is written to w once it has been listed in full. Dumps whose filters join other dumps' results are listed
+// after the dumps they reference.
+func StreamDumps(factory *Factory, cfg config.DumpCommand, w DumpWriter) error {
+	targets, err := resolveTargets(factory, cfg)
+	if err != nil {
+		return err
+	}
+
+	levels, err := orderTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	j := newJoins(targetDumps(targets))
+	for _, level := range levels {
+		err = streamTargets(factory, level, cfg, j, w)
 		if err != nil {
-			return nil, err
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamTargets lists targets sequentially or, if cfg.Workers is greater than 1, concurrently
+func streamTargets(factory *Factory, targets []dumpTarget, cfg config.DumpCommand, j *joins, w DumpWriter) error {
+	if cfg.Workers <= 1 || len(targets) == 1 {
+		for _, t := range targets {
+			err := streamTarget(factory, t, cfg, j, w)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	jobs := make(chan dumpTarget)
+	go func() {
+		defer close(jobs)
+		for _, t := range targets {
+			jobs <- t
 		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		firstErr error
+	)
+
+	wg.Add(cfg.Workers)
+	for i := 1; i <= cfg.Workers; i++ {
+		go func(worker int) {
+			defer wg.Done()
+			for t := range jobs {
+				mtx.Lock()
+				failed := firstErr != nil
+				mtx.Unlock()
+				if failed { // drain remaining jobs
+					continue
+				}
+
+				logrus.Debugf("worker %v listing %v", worker, ResourceString(t.mapping.Resource))
+				buf := &bufferWriter{}
+				err := streamTarget(factory, t, cfg, j, buf)
+
+				mtx.Lock()
+				if err == nil && firstErr == nil {
+					err = buf.replay(w)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mtx.Unlock()
+			}
+		}(i)
+	}
 
-		l, err := cli.Namespace(dump.Namespace).List(metav1.ListOptions{})
+	wg.Wait()
+
+	return firstErr
+}
+
+// dumpTarget is a dump resolved to the resource it lists
+type dumpTarget struct {
+	dump    config.Dump
+	mapping *meta.RESTMapping
+	// discovered is set for targets generated by cfg.All, errors listing them are logged rather than returned
+	discovered bool
+}
+
+// resolveTargets resolves cfg's dumps and, if cfg.All is set, every listable resource using discovery
+func resolveTargets(factory *Factory, cfg config.DumpCommand) ([]dumpTarget, error) {
+	resolver, err := NewResolver(factory)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []dumpTarget
+	for _, dump := range cfg.Dumps {
+		mapping, err := resolver.Resolve(dump)
 		if err != nil {
 			return nil, err
 		}
 
-		dumps[dump.GVR.Resource] = filterList(l, dump.Filters)
+		logrus.Debugf("resolved %v to %v", describeTarget(dump), ResourceString(mapping.Resource))
+		dump.GVR = mapping.Resource
+		targets = append(targets, dumpTarget{dump: dump, mapping: mapping})
 	}
 
-	return dumps, nil
+	if cfg.All != nil {
+		configured := targets
+		keys := make(map[string]bool)
+		for _, t := range configured {
+			keys[t.dump.Key()] = true
+		}
+
+		for _, mapping := range resolver.Listable(*cfg.All) {
+			dump := config.Dump{
+				GVR:     mapping.Resource,
+				Filters: cfg.All.Filters,
+			}
+			if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+				dump.Namespace = cfg.All.Namespace
+			}
+
+			if c, ok := coveredBy(configured, dump); ok {
+				logrus.Debugf("skipping discovered %v, it's dumped by %v", ResourceString(mapping.Resource), c.Key())
+				continue
+			}
+
+			// a configured dump of the resource that selects or filters what it lists doesn't cover it
+			if key := dump.Key(); keys[key] {
+				dump.Name = key + discoveredSuffix
+			}
+
+			targets = append(targets, dumpTarget{dump: dump, mapping: mapping, discovered: true})
+		}
+		logrus.Debugf("discovered %v listable resources", len(targets)-len(cfg.Dumps))
+	}
+
+	// validate keys once every dump is resolved
+	resolved := config.DumpCommand{Dumps: targetDumps(targets)}
+	err = resolved.CheckKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	return targets, nil
 }
 
-// filterList applies filters to a list of resources by json path
-func filterList(l *unstructured.UnstructuredList, filter config.Filter) []unstructured.Unstructured {
-	var filtered []unstructured.Unstructured
-	for _, elt := range l.Items {
-		eraw, err := json.Marshal(elt.Object)
+// coveredBy returns the configured dump that already lists every resource of a discovered dump in its namespace, a dump
+// without namespaces or a namespace selector covers every namespace. Dumps with label or field selectors or filters
+// only list some of the resources and never cover a discovered dump.
+func coveredBy(configured []dumpTarget, discovered config.Dump) (config.Dump, bool) {
+	for _, t := range configured {
+		if t.dump.GVR != discovered.GVR {
+			continue
+		}
+
+		if t.dump.LabelSelector != "" || t.dump.FieldSelector != "" || !t.dump.Filters.Empty() {
+			continue
+		}
+
+		if t.mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return t.dump, true
+		}
+
+		namespaces := t.dump.Namespaces
+		if t.dump.Namespace != "" {
+			namespaces = append([]string{t.dump.Namespace}, namespaces...)
+		}
+
+		if len(namespaces) == 0 && t.dump.NamespaceSelector == "" {
+			return t.dump, true
+		}
+
+		if discovered.Namespace != "" && contains(namespaces, discovered.Namespace) {
+			return t.dump, true
+		}
+	}
+
+	return config.Dump{}, false
+}
+
+// targetDumps returns the resolved dumps of targets
+func targetDumps(targets []dumpTarget) []config.Dump {
+	dumps := make([]config.Dump, len(targets))
+	for i, t := range targets {
+		dumps[i] = t.dump
+	}
+	return dumps
+}
+
+// orderTargets groups targets into levels that are listed one after another so joined dumps are listed first
+func orderTargets(targets []dumpTarget) ([][]dumpTarget, error) {
+	order, err := orderDumps(targetDumps(targets))
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([][]dumpTarget, len(order))
+	for i, indexes := range order {
+		for _, idx := range indexes {
+			levels[i] = append(levels[i], targets[idx])
+		}
+	}
+	return levels, nil
+}
+
+// streamTarget lists a target's resources in each of its namespaces and writes the items satisfying its filters to w
+func streamTarget(factory *Factory, t dumpTarget, cfg config.DumpCommand, j *joins, w DumpWriter) error {
+	err := streamDump(factory, t, cfg, j, w)
+	if err != nil && t.discovered {
+		logrus.Warnf("skipping %v: %v", ResourceString(t.mapping.Resource), err)
+		return nil
+	}
+
+	return err
+}
+
+// streamDump lists a single dump's resources in each of its namespaces and writes the items satisfying its filters to w,
+// the values of the items referenced by other dumps are collected in j
+func streamDump(factory *Factory, t dumpTarget, cfg config.DumpCommand, j *joins, w DumpWriter) error {
+	pageSize := cfg.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	namespaces, err := targetNamespaces(factory, t)
+	if err != nil {
+		return err
+	}
+
+	dump := t.dump
+	dump.Filters = j.resolve(dump.Filters)
+	key := dump.Key()
+	err = w.Begin(key, dump)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range namespaces {
+		cli, err := factory.ResourceClient(t.mapping, ns)
 		if err != nil {
-			logrus.Fatal(err)
+			return err
 		}
 
-		raw := string(eraw)
+		opts := metav1.ListOptions{
+			LabelSelector: dump.LabelSelector,
+			FieldSelector: dump.FieldSelector,
+			Limit:         pageSize,
+		}
 
-		// apply and filters, all must be satisfied in order to keep element in filtered list
-		andsSatisfied := true
-		if len(filter.Ands) > 0 {
-			for _, f := range filter.Ands {
-				result := gjson.Get(raw, f.Key)
-				if !result.Exists() || !strings.EqualFold(result.String(), f.Value) {
-					andsSatisfied = false
-					break
-				}
+		err = listPages(cli, opts, func(l *unstructured.UnstructuredList) error {
+			filtered, err := filterList(l, dump.Filters)
+			if err != nil {
+				return err
 			}
-		}
 
-		// apply or filters, one must be satisfied in order to keep element in filtered list
-		orsSatisfied := true
-		if len(filter.Ors) > 0 {
-			var match bool
-			for _, f := range filter.Ors {
-				result := gjson.Get(raw, f.Key)
-				if result.Exists() && strings.EqualFold(result.String(), f.Value) {
-					match = true
+			for _, f := range filtered {
+				err = j.collect(key, f.item)
+				if err != nil {
+					return err
+				}
+
+				var matched []interface{}
+				if dump.ReportMatches && len(f.matched) > 0 {
+					matched, err = redactMatches(f.item, f.matched, dump, cfg)
+					if err != nil {
+						return err
+					}
+				}
+
+				item, err := prune(f.item, dump, cfg)
+				if err != nil {
+					return err
+				}
+
+				if len(matched) > 0 {
+					item.Object[MatchedField] = matched
+				}
+
+				err = w.Write(key, item)
+				if err != nil {
+					return err
 				}
 			}
 
-			if !match {
-				orsSatisfied = false
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// targetNamespaces returns the namespaces a target is listed in, a single empty namespace means all namespaces.
+// Namespaces selected by the dump's namespace selector are looked up from the api server.
+func targetNamespaces(factory *Factory, t dumpTarget) ([]string, error) {
+	dump := t.dump
+	if t.mapping.Scope.Name() == meta.RESTScopeNameRoot || (len(dump.Namespaces) == 0 && dump.NamespaceSelector == "") {
+		return []string{dump.Namespace}, nil
+	}
+
+	var namespaces []string
+	seen := make(map[string]bool)
+	add := func(ns string) {
+		if ns != "" && !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	add(dump.Namespace)
+	for _, ns := range dump.Namespaces {
+		add(ns)
+	}
+
+	if dump.NamespaceSelector != "" {
+		cli, err := factory.Client()
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := cli.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: dump.NamespaceSelector})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range l.Items {
+			add(ns.Name)
+		}
+		logrus.Debugf("namespace selector %q selected %v namespaces for %v", dump.NamespaceSelector, len(l.Items), dump.Key())
+	}
+
+	return namespaces, nil
+}
+
+// listPages lists resources in pages of opts.Limit items and calls fn with each page.
+// If the continue token expires mid list, the list is restarted and items that were already seen are skipped.
+func listPages(cli dynamic.ResourceInterface, opts metav1.ListOptions, fn func(*unstructured.UnstructuredList) error) error {
+	seen := make(map[types.UID]struct{})
+	for {
+		l, err := cli.List(context.TODO(), opts)
+		if errors.IsResourceExpired(err) && opts.Continue != "" {
+			logrus.Debugf("continue token expired, restarting list after %v items", len(seen))
+			opts.Continue = ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		items := l.Items[:0]
+		for _, item := range l.Items {
+			if _, ok := seen[item.GetUID()]; ok {
+				continue
 			}
+			seen[item.GetUID()] = struct{}{}
+			items = append(items, item)
+		}
+		l.Items = items
+
+		err = fn(l)
+		if err != nil {
+			return err
 		}
 
-		if andsSatisfied && orsSatisfied {
-			filtered = append(filtered, elt)
+		if l.GetContinue() == "" {
+			break
 		}
+
+		opts.Continue = l.GetContinue()
+	}
+	return nil
+}
+
+// collector is a DumpWriter that holds every item in memory keyed by dump
+type collector map[string][]unstructured.Unstructured
+
+func (c collector) Begin(key string, dump config.Dump) error {
+	c[key] = nil
+	return nil
+}
+
+func (c collector) Write(key string, item unstructured.Unstructured) error {
+	c[key] = append(c[key], item)
+	return nil
+}
+
+// bufferWriter is a DumpWriter that holds a single dump's items until they're replayed to another DumpWriter
+type bufferWriter struct {
+	key   string
+	dump  config.Dump
+	items []unstructured.Unstructured
+}
+
+func (b *bufferWriter) Begin(key string, dump config.Dump) error {
+	b.key, b.dump = key, dump
+	return nil
+}
+
+func (b *bufferWriter) Write(key string, item unstructured.Unstructured) error {
+	b.items = append(b.items, item)
+	return nil
+}
+
+func (b *bufferWriter) replay(w DumpWriter) error {
+	if b.key == "" { // never began
+		return nil
+	}
+
+	err := w.Begin(b.key, b.dump)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range b.items {
+		err = w.Write(b.key, item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filterResult is a resource that satisfied a filter and the array elements that satisfied it
+type filterResult struct {
+	item    unstructured.Unstructured
+	matched []interface{}
+}
+
+// filterList applies filters to a list of resources by json path
+func filterList(l *unstructured.UnstructuredList, filter config.Filter) ([]filterResult, error) {
+	var filtered []filterResult
+	for _, elt := range l.Items {
+		r := &matchReport{}
+		match, err := matches(elt, filter, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if match {
+			filtered = append(filtered, filterResult{item: elt, matched: r.matches})
+		}
+	}
+
+	return filtered, nil
+}
+
+// matches reports whether a single resource satisfies filter, recording the array elements that satisfied it in r
+func matches(item unstructured.Unstructured, filter config.Filter, r *matchReport) (bool, error) {
+	raw, err := json.Marshal(item.Object)
+	if err != nil {
+		return false, err
 	}
 
-	return filtered
+	return matchFilter(string(raw), filter, r)
 }
@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCoveredBy(t *testing.T) {
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	nodes := schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	namespaced := &meta.RESTMapping{Resource: pods, Scope: meta.RESTScopeNamespace}
+	cluster := &meta.RESTMapping{Resource: nodes, Scope: meta.RESTScopeRoot}
+
+	tests := []struct {
+		name       string
+		configured []dumpTarget
+		discovered config.Dump
+		want       string
+	}{
+		{
+			name:       "every namespace",
+			configured: []dumpTarget{{dump: config.Dump{Name: "pods", GVR: pods}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+			want:       "pods",
+		},
+		{
+			name:       "every namespace discovered without one",
+			configured: []dumpTarget{{dump: config.Dump{Name: "pods", GVR: pods}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods},
+			want:       "pods",
+		},
+		{
+			name:       "cluster scoped",
+			configured: []dumpTarget{{dump: config.Dump{Name: "nodes", GVR: nodes}, mapping: cluster}},
+			discovered: config.Dump{GVR: nodes},
+			want:       "nodes",
+		},
+		{
+			name:       "label selector",
+			configured: []dumpTarget{{dump: config.Dump{Name: "nodes", GVR: nodes, LabelSelector: "a=b"}, mapping: cluster}},
+			discovered: config.Dump{GVR: nodes},
+		},
+		{
+			name:       "field selector",
+			configured: []dumpTarget{{dump: config.Dump{Name: "pods", GVR: pods, FieldSelector: "spec.nodeName=a"}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+		},
+		{
+			name: "filters",
+			configured: []dumpTarget{{dump: config.Dump{Name: "pods", GVR: pods, Namespace: "web",
+				Filters: config.Filter{Not: &config.Filter{FilterElement: config.FilterElement{Key: "spec.nodeName"}}}}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+		},
+		{
+			name: "filtered and unfiltered",
+			configured: []dumpTarget{
+				{dump: config.Dump{Name: "filtered", GVR: pods, Filters: config.Filter{Ors: []config.FilterElement{{Key: "a"}}}}, mapping: namespaced},
+				{dump: config.Dump{Name: "web", GVR: pods, Namespace: "web"}, mapping: namespaced},
+			},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+			want:       "web",
+		},
+		{
+			name:       "other resource",
+			configured: []dumpTarget{{dump: config.Dump{Name: "nodes", GVR: nodes}, mapping: cluster}},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+		},
+		{
+			name: "namespace",
+			configured: []dumpTarget{
+				{dump: config.Dump{Name: "db", GVR: pods, Namespace: "db"}, mapping: namespaced},
+				{dump: config.Dump{Name: "web", GVR: pods, Namespace: "a", Namespaces: []string{"web"}}, mapping: namespaced},
+			},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+			want:       "web",
+		},
+		{
+			name:       "other namespace",
+			configured: []dumpTarget{{dump: config.Dump{Name: "db", GVR: pods, Namespace: "db"}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+		},
+		{
+			name:       "some namespaces discovered without one",
+			configured: []dumpTarget{{dump: config.Dump{Name: "db", GVR: pods, Namespace: "db"}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods},
+		},
+		{
+			name:       "namespace selector",
+			configured: []dumpTarget{{dump: config.Dump{Name: "team", GVR: pods, NamespaceSelector: "team=a"}, mapping: namespaced}},
+			discovered: config.Dump{GVR: pods, Namespace: "web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := coveredBy(tt.configured, tt.discovered)
+			if ok != (tt.want != "") || d.Name != tt.want {
+				t.Errorf("coveredBy() = %q, %v, want %q", d.Name, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ryansann/k8sutil/config"
+	"github.com/tidwall/gjson"
+)
+
+// matchReport collects the array elements that satisfied filter elements with match semantics
+type matchReport struct {
+	matches []interface{}
+}
+
+// add records the elements of an array at key that satisfied a filter element
+func (r *matchReport) add(key string, values []interface{}) {
+	if r == nil || len(values) == 0 {
+		return
+	}
+	r.matches = append(r.matches, map[string]interface{}{"key": key, "values": values})
+}
+
+// mark returns the number of matches recorded so they can be discarded with reset if a filter isn't satisfied
+func (r *matchReport) mark() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.matches)
+}
+
+func (r *matchReport) reset(mark int) {
+	if r != nil {
+		r.matches = r.matches[:mark]
+	}
+}
+
+// matchFilter reports whether the json document raw satisfies every part of the filter tree that is set,
+// the array elements that satisfied it are recorded in r if it's not nil
+func matchFilter(raw string, f config.Filter, r *matchReport) (bool, error) {
+	mark := r.mark()
+	match, err := matchTree(raw, f, r)
+	if err != nil || !match {
+		r.reset(mark)
+	}
+	return match, err
+}
+
+func matchTree(raw string, f config.Filter, r *matchReport) (bool, error) {
+	if f.Key != "" {
+		match, err := matchElement(raw, f.FilterElement, r)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+
+	// all and conditions must be satisfied
+	for _, e := range f.Ands {
+		match, err := matchElement(raw, e, r)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+
+	// at least one or condition must be satisfied
+	if len(f.Ors) > 0 {
+		var match bool
+		for _, e := range f.Ors {
+			m, err := matchElement(raw, e, r)
+			if err != nil {
+				return false, err
+			}
+			if m {
+				match = true
+				break
+			}
+		}
+
+		if !match {
+			return false, nil
+		}
+	}
+
+	for _, g := range f.All {
+		match, err := matchFilter(raw, g, r)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+
+	if len(f.Any) > 0 {
+		var match bool
+		for _, g := range f.Any {
+			m, err := matchFilter(raw, g, r)
+			if err != nil {
+				return false, err
+			}
+			if m {
+				match = true
+				break
+			}
+		}
+
+		if !match {
+			return false, nil
+		}
+	}
+
+	// elements satisfying a negated filter aren't reported
+	if f.Not != nil {
+		match, err := matchFilter(raw, *f.Not, nil)
+		if err != nil || match {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchElement reports whether the json document raw satisfies a single filter element.
+// When the value at the element's key is an array and the element's match is any or all, the op is applied to each
+// of the array's elements, a key containing a # array path defaults to any. Exists and notexists always apply to
+// the value as a whole.
+func matchElement(raw string, f config.FilterElement, r *matchReport) (bool, error) {
+	result := gjson.Get(raw, f.Key)
+
+	mode := strings.ToLower(f.Match)
+	if mode == "" && result.IsArray() && strings.Contains(f.Key, "#") {
+		mode = config.MatchAny
+	}
+
+	op := strings.ToLower(f.Op)
+	if mode == "" || op == config.OpExists || op == config.OpNotExists || !result.IsArray() {
+		return matchValue(result, f)
+	}
+
+	elements := flatten(result)
+	if len(elements) == 0 {
+		return false, nil
+	}
+
+	var matched []interface{}
+	for _, e := range elements {
+		m, err := matchValue(e, f)
+		if err != nil {
+			return false, err
+		}
+
+		if m {
+			matched = append(matched, e.Value())
+		} else if mode == config.MatchAll {
+			return false, nil
+		}
+	}
+
+	if len(matched) == 0 {
+		return false, nil
+	}
+
+	r.add(f.Key, matched)
+	return true, nil
+}
+
+// flatten returns the elements of an array result, the elements of nested arrays such as those returned by
+// paths with more than one # are included rather than the nested arrays
+func flatten(result gjson.Result) []gjson.Result {
+	var elements []gjson.Result
+	result.ForEach(func(_, e gjson.Result) bool {
+		if e.IsArray() {
+			elements = append(elements, flatten(e)...)
+		} else {
+			elements = append(elements, e)
+		}
+		return true
+	})
+	return elements
+}
+
+// matchValue reports whether result satisfies a filter element's op
+func matchValue(result gjson.Result, f config.FilterElement) (bool, error) {
+	switch strings.ToLower(f.Op) {
+	case "", config.OpEq:
+		return result.Exists() && strings.EqualFold(result.String(), f.Value), nil
+	case config.OpNe:
+		return !result.Exists() || !strings.EqualFold(result.String(), f.Value), nil
+	case config.OpExists:
+		return result.Exists(), nil
+	case config.OpNotExists:
+		return !result.Exists(), nil
+	case config.OpPrefix:
+		return result.Exists() && strings.HasPrefix(strings.ToLower(result.String()), strings.ToLower(f.Value)), nil
+	case config.OpSuffix:
+		return result.Exists() && strings.HasSuffix(strings.ToLower(result.String()), strings.ToLower(f.Value)), nil
+	case config.OpIn:
+		if !result.Exists() {
+			return false, nil
+		}
+		for _, v := range f.Values {
+			if strings.EqualFold(result.String(), v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case config.OpRegex:
+		re, err := compileRegex(f.Value)
+		if err != nil {
+			return false, err
+		}
+		return result.Exists() && re.MatchString(result.String()), nil
+	case config.OpGt, config.OpGe, config.OpLt, config.OpLe:
+		if !result.Exists() {
+			return false, nil
+		}
+
+		cmp, ok, err := compare(result.String(), f.Value)
+		if err != nil || !ok {
+			return false, err
+		}
+
+		switch strings.ToLower(f.Op) {
+		case config.OpGt:
+			return cmp > 0, nil
+		case config.OpGe:
+			return cmp >= 0, nil
+		case config.OpLt:
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	default:
+		return false, fmt.Errorf("unknown filter op: %q", f.Op)
+	}
+}
+
+// compare compares actual to expected numerically or, failing that, as RFC3339 timestamps
+// ok is false when actual can't be interpreted the same way as expected
+func compare(actual, expected string) (cmp int, ok bool, err error) {
+	if e, err := strconv.ParseFloat(expected, 64); err == nil {
+		a, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return 0, false, nil
+		}
+		switch {
+		case a < e:
+			return -1, true, nil
+		case a > e:
+			return 1, true, nil
+		}
+		return 0, true, nil
+	}
+
+	if e, err := time.Parse(time.RFC3339, expected); err == nil {
+		a, err := time.Parse(time.RFC3339, actual)
+		if err != nil {
+			return 0, false, nil
+		}
+		switch {
+		case a.Before(e):
+			return -1, true, nil
+		case a.After(e):
+			return 1, true, nil
+		}
+		return 0, true, nil
+	}
+
+	return 0, false, fmt.Errorf("filter value %q is neither a number nor an RFC3339 timestamp", expected)
+}
+
+var (
+	regexCache = make(map[string]*regexp.Regexp)
+	regexMtx   sync.Mutex
+)
+
+// compileRegex compiles expr once and caches it for subsequent elements
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	regexMtx.Lock()
+	defer regexMtx.Unlock()
+
+	if re, ok := regexCache[expr]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter regex %q: %v", expr, err)
+	}
+
+	regexCache[expr] = re
+	return re, nil
+}
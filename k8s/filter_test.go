@@ -0,0 +1,186 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+)
+
+const filterDoc = `{
+	"metadata": {"name": "svc-deploy", "creationTimestamp": "2021-06-01T12:00:00Z", "labels": {"app.kubernetes.io/name": "web"}},
+	"spec": {"replicas": 3},
+	"subjects": [
+		{"kind": "User", "name": "u-4qdsz"},
+		{"kind": "Group", "name": "admins"},
+		{"kind": "User", "name": "u-abcde"}
+	],
+	"ports": [80, 443],
+	"empty": []
+}`
+
+func el(key, op, value string) config.FilterElement {
+	return config.FilterElement{Key: key, Op: op, Value: value}
+}
+
+func TestMatchElement(t *testing.T) {
+	tests := []struct {
+		name string
+		e    config.FilterElement
+		want bool
+	}{
+		{"eq is case insensitive", el("metadata.name", "", "SVC-DEPLOY"), true},
+		{"eq", el("metadata.name", config.OpEq, "other"), false},
+		{"eq missing key", el("metadata.missing", config.OpEq, ""), false},
+		{"ne", el("metadata.name", config.OpNe, "other"), true},
+		{"ne missing key", el("metadata.missing", config.OpNe, "other"), true},
+		{"regex", el("metadata.name", config.OpRegex, "^svc-"), true},
+		{"regex no match", el("metadata.name", config.OpRegex, "^web-"), false},
+		{"prefix", el("metadata.name", config.OpPrefix, "SVC"), true},
+		{"suffix", el("metadata.name", config.OpSuffix, "deploy"), true},
+		{"suffix no match", el("metadata.name", config.OpSuffix, "svc"), false},
+		{"exists", el("spec.replicas", config.OpExists, ""), true},
+		{"exists missing key", el("spec.missing", config.OpExists, ""), false},
+		{"notexists", el("spec.missing", config.OpNotExists, ""), true},
+		{"escaped key", el(`metadata.labels.app\.kubernetes\.io/name`, config.OpEq, "web"), true},
+		{"gt", el("spec.replicas", config.OpGt, "2"), true},
+		{"gt equal", el("spec.replicas", config.OpGt, "3"), false},
+		{"ge equal", el("spec.replicas", config.OpGe, "3"), true},
+		{"lt", el("spec.replicas", config.OpLt, "3.5"), true},
+		{"le", el("spec.replicas", config.OpLe, "2"), false},
+		{"gt non numeric value", el("metadata.name", config.OpGt, "2"), false},
+		{"gt missing key", el("spec.missing", config.OpGt, "2"), false},
+		{"lt timestamp", el("metadata.creationTimestamp", config.OpLt, "2022-01-01T00:00:00Z"), true},
+		{"gt timestamp", el("metadata.creationTimestamp", config.OpGt, "2022-01-01T00:00:00Z"), false},
+		{"in", config.FilterElement{Key: "metadata.name", Op: config.OpIn, Values: []string{"a", "SVC-deploy"}}, true},
+		{"in no match", config.FilterElement{Key: "metadata.name", Op: config.OpIn, Values: []string{"a", "b"}}, false},
+		{"in missing key", config.FilterElement{Key: "metadata.missing", Op: config.OpIn, Values: []string{""}}, false},
+		{"query", el(`subjects.#(kind=="Group").name`, config.OpEq, "admins"), true},
+		{"# defaults to any", el("subjects.#.name", config.OpEq, "u-abcde"), true},
+		{"# defaults to any no match", el("subjects.#.name", config.OpEq, "nobody"), false},
+		{"match all", config.FilterElement{Key: "subjects.#.name", Op: config.OpPrefix, Value: "u-", Match: config.MatchAll}, false},
+		{"match all satisfied", config.FilterElement{Key: "subjects.#.kind", Op: config.OpRegex, Value: "^(User|Group)$", Match: config.MatchAll}, true},
+		{"match any without #", config.FilterElement{Key: "ports", Op: config.OpEq, Value: "443", Match: config.MatchAny}, true},
+		{"array without match compares the whole value", el("ports", config.OpEq, "443"), false},
+		{"match all on an empty array", config.FilterElement{Key: "empty", Op: config.OpNe, Value: "x", Match: config.MatchAll}, false},
+		{"exists applies to the whole array", config.FilterElement{Key: "empty", Op: config.OpExists, Match: config.MatchAll}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchElement(filterDoc, tt.e, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matchElement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchElementErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		e    config.FilterElement
+	}{
+		{"invalid regex", el("metadata.name", config.OpRegex, "(")},
+		{"invalid comparison value", el("spec.replicas", config.OpGt, "three")},
+		{"unknown op", el("metadata.name", "like", "svc")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := matchElement(filterDoc, tt.e, nil)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	name := func(value string) config.Filter {
+		return config.Filter{FilterElement: el("metadata.name", config.OpEq, value)}
+	}
+
+	tests := []struct {
+		name string
+		f    config.Filter
+		want bool
+	}{
+		{"empty filter", config.Filter{}, true},
+		{"element", name("svc-deploy"), true},
+		{"ands", config.Filter{Ands: []config.FilterElement{el("metadata.name", "", "svc-deploy"), el("spec.replicas", "", "3")}}, true},
+		{"ands one fails", config.Filter{Ands: []config.FilterElement{el("metadata.name", "", "svc-deploy"), el("spec.replicas", "", "2")}}, false},
+		{"ors", config.Filter{Ors: []config.FilterElement{el("metadata.name", "", "other"), el("spec.replicas", "", "3")}}, true},
+		{"ors none match", config.Filter{Ors: []config.FilterElement{el("metadata.name", "", "other"), el("spec.replicas", "", "2")}}, false},
+		{"element and ors", config.Filter{FilterElement: el("spec.replicas", "", "2"), Ors: []config.FilterElement{el("metadata.name", "", "svc-deploy")}}, false},
+		{"all", config.Filter{All: []config.Filter{name("svc-deploy"), {Any: []config.Filter{name("a"), name("svc-deploy")}}}}, true},
+		{"all one fails", config.Filter{All: []config.Filter{name("svc-deploy"), name("other")}}, false},
+		{"any", config.Filter{Any: []config.Filter{name("other"), name("svc-deploy")}}, true},
+		{"any none match", config.Filter{Any: []config.Filter{name("other"), name("another")}}, false},
+		{"not", config.Filter{Not: &config.Filter{FilterElement: el("metadata.name", "", "other")}}, true},
+		{"not matching", config.Filter{Not: &config.Filter{FilterElement: el("metadata.name", "", "svc-deploy")}}, false},
+		{"nested not", config.Filter{Any: []config.Filter{{Not: &config.Filter{Any: []config.Filter{name("svc-deploy")}}}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchFilter(filterDoc, tt.f, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matchFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchFilterReport(t *testing.T) {
+	users := el("subjects.#(kind==\"User\")#.name", config.OpPrefix, "u-")
+
+	tests := []struct {
+		name string
+		f    config.Filter
+		want []interface{}
+	}{
+		{
+			name: "matching elements are reported",
+			f:    config.Filter{FilterElement: users},
+			want: []interface{}{map[string]interface{}{"key": users.Key, "values": []interface{}{"u-4qdsz", "u-abcde"}}},
+		},
+		{
+			name: "unsatisfied groups aren't reported",
+			f: config.Filter{Any: []config.Filter{
+				{Ands: []config.FilterElement{users, el("metadata.name", "", "other")}},
+				{FilterElement: config.FilterElement{Key: "ports", Op: config.OpEq, Value: "80", Match: config.MatchAny}},
+			}, Ors: []config.FilterElement{el("subjects.#.name", config.OpEq, "admins")}},
+			want: []interface{}{
+				map[string]interface{}{"key": "subjects.#.name", "values": []interface{}{"admins"}},
+				map[string]interface{}{"key": "ports", "values": []interface{}{float64(80)}},
+			},
+		},
+		{
+			name: "not isn't reported",
+			f:    config.Filter{Not: &config.Filter{FilterElement: el("subjects.#.name", config.OpEq, "nobody")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &matchReport{}
+			match, err := matchFilter(filterDoc, tt.f, r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !match {
+				t.Fatal("expected the filter to match")
+			}
+			if !reflect.DeepEqual(r.matches, tt.want) {
+				t.Errorf("matches = %v, want %v", r.matches, tt.want)
+			}
+		})
+	}
+}
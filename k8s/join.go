@@ -0,0 +1,242 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ryansann/k8sutil/config"
+	"github.com/tidwall/gjson"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// joins holds the values that dumps' filters read from other dumps' results
+type joins struct {
+	mtx sync.Mutex
+	// paths holds the paths other dumps reference, keyed by the referenced dump's key
+	paths map[string][]string
+	// values holds the values found at each referenced path, keyed by dump key and then path
+	values map[string]map[string][]string
+	seen   map[string]map[string]map[string]bool
+}
+
+// newJoins returns the joins between dumps, dumps must be resolved so their keys are final
+func newJoins(dumps []config.Dump) *joins {
+	j := &joins{
+		paths:  make(map[string][]string),
+		values: make(map[string]map[string][]string),
+		seen:   make(map[string]map[string]map[string]bool),
+	}
+
+	for _, d := range dumps {
+		for _, ref := range d.Filters.References() {
+			if !contains(j.paths[ref.Dump], ref.Path) {
+				j.paths[ref.Dump] = append(j.paths[ref.Dump], ref.Path)
+			}
+		}
+	}
+
+	return j
+}
+
+// referenced reports whether other dumps read values from the results of the dump keyed by key
+func (j *joins) referenced(key string) bool {
+	return len(j.paths[key]) > 0
+}
+
+// collect records the values at every referenced path of item, a result of the dump keyed by key
+func (j *joins) collect(key string, item unstructured.Unstructured) error {
+	paths := j.paths[key]
+	if len(paths) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(item.Object)
+	if err != nil {
+		return err
+	}
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if j.values[key] == nil {
+		j.values[key] = make(map[string][]string)
+		j.seen[key] = make(map[string]map[string]bool)
+	}
+
+	for _, p := range paths {
+		if j.seen[key][p] == nil {
+			j.seen[key][p] = make(map[string]bool)
+		}
+
+		add := func(r gjson.Result) bool {
+			v := r.String()
+			if !j.seen[key][p][v] {
+				j.seen[key][p][v] = true
+				j.values[key][p] = append(j.values[key][p], v)
+			}
+			return true
+		}
+
+		result := gjson.GetBytes(raw, p)
+		switch {
+		case result.IsArray():
+			result.ForEach(func(_, r gjson.Result) bool { return add(r) })
+		case result.Exists():
+			add(result)
+		}
+	}
+
+	return nil
+}
+
+// resolve returns a copy of f with the values collected for each of its ValuesFrom added to the element's Values
+func (j *joins) resolve(f config.Filter) config.Filter {
+	if len(f.References()) == 0 {
+		return f
+	}
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	return j.resolveFilter(f)
+}
+
+func (j *joins) resolveFilter(f config.Filter) config.Filter {
+	f.FilterElement = j.resolveElement(f.FilterElement)
+
+	f.Ands = j.resolveElements(f.Ands)
+	f.Ors = j.resolveElements(f.Ors)
+
+	var allOf, anyOf []config.Filter
+	for _, g := range f.All {
+		allOf = append(allOf, j.resolveFilter(g))
+	}
+	for _, g := range f.Any {
+		anyOf = append(anyOf, j.resolveFilter(g))
+	}
+	f.All, f.Any = allOf, anyOf
+
+	if f.Not != nil {
+		not := j.resolveFilter(*f.Not)
+		f.Not = &not
+	}
+
+	return f
+}
+
+func (j *joins) resolveElements(elements []config.FilterElement) []config.FilterElement {
+	var resolved []config.FilterElement
+	for _, e := range elements {
+		resolved = append(resolved, j.resolveElement(e))
+	}
+	return resolved
+}
+
+func (j *joins) resolveElement(e config.FilterElement) config.FilterElement {
+	if e.ValuesFrom == nil {
+		return e
+	}
+
+	values := j.values[e.ValuesFrom.Dump][e.ValuesFrom.Path]
+	e.Values = append(append([]string{}, e.Values...), values...)
+	return e
+}
+
+// resolveJoins lists the dumps referenced by other dumps in dependency order and returns targets with the values their
+// filters join resolved, used where joined values can't be collected as dumps are streamed
+func resolveJoins(factory *Factory, cfg config.DumpCommand, targets []dumpTarget) ([]dumpTarget, error) {
+	levels, err := orderTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	j := newJoins(targetDumps(targets))
+	var resolved []dumpTarget
+	for _, level := range levels {
+		for _, t := range level {
+			if j.referenced(t.dump.Key()) {
+				err = streamTarget(factory, t, cfg, j, discard{})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			t.dump.Filters = j.resolve(t.dump.Filters)
+			resolved = append(resolved, t)
+		}
+	}
+
+	return resolved, nil
+}
+
+// discard is a DumpWriter that drops every item
+type discard struct{}
+
+func (discard) Begin(key string, dump config.Dump) error { return nil }
+
+func (discard) Write(key string, item unstructured.Unstructured) error { return nil }
+
+// orderDumps groups the indexes of dumps into levels such that every dump is in a later level than the dumps its
+// filters reference, dumps within a level keep their order. Dumps must be resolved so their keys are final.
+func orderDumps(dumps []config.Dump) ([][]int, error) {
+	index := make(map[string]int, len(dumps))
+	for i, d := range dumps {
+		index[d.Key()] = i
+	}
+
+	deps := make([]map[int]bool, len(dumps))
+	for i, d := range dumps {
+		deps[i] = make(map[int]bool)
+		for _, ref := range d.Filters.References() {
+			dep, ok := index[ref.Dump]
+			if !ok {
+				return nil, fmt.Errorf("dump %q references unknown dump %q", d.Key(), ref.Dump)
+			}
+			deps[i][dep] = true
+		}
+	}
+
+	var levels [][]int
+	done := make([]bool, len(dumps))
+	for remaining := len(dumps); remaining > 0; {
+		var level []int
+		for i := range dumps {
+			if done[i] {
+				continue
+			}
+
+			ready := true
+			for dep := range deps[i] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, i)
+			}
+		}
+
+		if len(level) == 0 {
+			var cycle []string
+			for i, d := range dumps {
+				if !done[i] {
+					cycle = append(cycle, fmt.Sprintf("%q", d.Key()))
+				}
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("dumps %v reference each other", strings.Join(cycle, ", "))
+		}
+
+		for _, i := range level {
+			done[i] = true
+		}
+		remaining -= len(level)
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// joinDump returns a dump named name whose filters read values from the dumps named refs
+func joinDump(name string, refs ...string) config.Dump {
+	d := config.Dump{Name: name}
+	for _, ref := range refs {
+		d.Filters.Any = append(d.Filters.Any, config.Filter{FilterElement: config.FilterElement{
+			Key:        "metadata.name",
+			Op:         config.OpIn,
+			ValuesFrom: &config.ValuesFrom{Dump: ref, Path: "metadata.name"},
+		}})
+	}
+	return d
+}
+
+func TestOrderDumps(t *testing.T) {
+	tests := []struct {
+		name    string
+		dumps   []config.Dump
+		want    [][]int
+		wantErr string
+	}{
+		{
+			name:  "no references",
+			dumps: []config.Dump{joinDump("a"), joinDump("b")},
+			want:  [][]int{{0, 1}},
+		},
+		{
+			name:  "chain",
+			dumps: []config.Dump{joinDump("pods", "sas"), joinDump("bindings", "roles"), joinDump("sas", "bindings"), joinDump("roles")},
+			want:  [][]int{{3}, {1}, {2}, {0}},
+		},
+		{
+			name:  "diamond",
+			dumps: []config.Dump{joinDump("d", "b", "c"), joinDump("b", "a"), joinDump("c", "a"), joinDump("a")},
+			want:  [][]int{{3}, {1, 2}, {0}},
+		},
+		{
+			name:    "unknown dump",
+			dumps:   []config.Dump{joinDump("a", "missing")},
+			wantErr: `dump "a" references unknown dump "missing"`,
+		},
+		{
+			name:    "cycle",
+			dumps:   []config.Dump{joinDump("root"), joinDump("b", "a"), joinDump("a", "b", "root")},
+			wantErr: `dumps "a", "b" reference each other`,
+		},
+		{
+			name:    "self reference",
+			dumps:   []config.Dump{joinDump("a", "a")},
+			wantErr: `dumps "a" reference each other`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderDumps(tt.dumps)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("orderDumps() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderDumps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinsResolve(t *testing.T) {
+	sas := config.Dump{Name: "sas"}
+	pods := config.Dump{Name: "pods", Filters: config.Filter{Not: &config.Filter{FilterElement: config.FilterElement{
+		Key:        "spec.serviceAccountName",
+		Op:         config.OpIn,
+		Values:     []string{"default"},
+		ValuesFrom: &config.ValuesFrom{Dump: "sas", Path: "metadata.name"},
+	}}}}
+
+	j := newJoins([]config.Dump{sas, pods})
+	if !j.referenced("sas") || j.referenced("pods") {
+		t.Fatal("expected only sas to be referenced")
+	}
+
+	for _, name := range []string{"builder", "deployer", "builder"} {
+		item := unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": name}}}
+		err := j.collect("sas", item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolved := j.resolve(pods.Filters)
+	want := []string{"default", "builder", "deployer"}
+	if !reflect.DeepEqual(resolved.Not.Values, want) {
+		t.Errorf("resolved values = %v, want %v", resolved.Not.Values, want)
+	}
+	if !reflect.DeepEqual(pods.Filters.Not.Values, []string{"default"}) {
+		t.Errorf("resolve modified the original filter: %v", pods.Filters.Not.Values)
+	}
+}
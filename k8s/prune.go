@@ -0,0 +1,258 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	// serverPaths are the server populated fields removed by Clean
+	serverPaths = []string{
+		"metadata.uid",
+		"metadata.resourceVersion",
+		"metadata.managedFields",
+		"metadata.creationTimestamp",
+		"metadata.generation",
+		"metadata.selfLink",
+		`metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`,
+		"status",
+	}
+
+	// identityPaths are always kept when projecting fields so that items remain identifiable
+	identityPaths = []string{
+		"apiVersion",
+		"kind",
+		"metadata.name",
+		"metadata.namespace",
+	}
+)
+
+// Clean removes server populated fields, and the matches reported by dump, from item so that it can be applied to a cluster
+func Clean(item *unstructured.Unstructured) {
+	for _, p := range serverPaths {
+		unstructured.RemoveNestedField(item.Object, splitPath(p)...)
+	}
+	delete(item.Object, MatchedField)
+
+	if len(item.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(item.Object, "metadata", "annotations")
+	}
+}
+
+// prune applies the dump's field projection, omissions and redactions to item, and cleans it if cfg.Clean is set
+func prune(item unstructured.Unstructured, dump config.Dump, cfg config.DumpCommand) (unstructured.Unstructured, error) {
+	var err error
+	if len(dump.Fields) > 0 {
+		item, err = project(item, append(identityPaths, dump.Fields...))
+		if err != nil {
+			return item, err
+		}
+	}
+
+	err = omit(&item, dump.Omit)
+	if err != nil {
+		return item, err
+	}
+
+	if cfg.Clean {
+		Clean(&item)
+	}
+
+	err = redact(&item, cfg.Redact, dump.Redact)
+	return item, err
+}
+
+// project returns a copy of item with only the fields at paths, arrays keep their length so that selected elements
+// keep their indexes and the elements that aren't selected are null
+func project(item unstructured.Unstructured, paths []string) (unstructured.Unstructured, error) {
+	projected := unstructured.Unstructured{Object: make(map[string]interface{})}
+	for _, p := range paths {
+		_, _, err := projectPath(item.Object, projected.Object, splitPath(p))
+		if err != nil {
+			return item, fmt.Errorf("fields: path %q: %v", p, err)
+		}
+	}
+	return projected, nil
+}
+
+// projectPath copies the values at fields below src into dst, it returns dst, or a new value when dst is nil, and whether
+// any value was found
+func projectPath(src, dst interface{}, fields []string) (interface{}, bool, error) {
+	if len(fields) == 0 {
+		return runtime.DeepCopyJSONValue(src), true, nil
+	}
+
+	switch s := src.(type) {
+	case map[string]interface{}:
+		v, ok := s[fields[0]]
+		if !ok {
+			return dst, false, nil
+		}
+
+		d, _ := dst.(map[string]interface{})
+		if d == nil {
+			d = make(map[string]interface{})
+		}
+
+		pv, found, err := projectPath(v, d[fields[0]], fields[1:])
+		if err != nil || !found {
+			return dst, false, err
+		}
+
+		d[fields[0]] = pv
+		return d, true, nil
+	case []interface{}:
+		indexes, err := arrayIndexes(s, fields[0])
+		if err != nil {
+			return dst, false, err
+		}
+
+		d, _ := dst.([]interface{})
+		if d == nil {
+			d = make([]interface{}, len(s))
+		}
+
+		var found bool
+		for _, i := range indexes {
+			pv, ok, err := projectPath(s[i], d[i], fields[1:])
+			if err != nil {
+				return dst, false, err
+			}
+			if ok {
+				d[i] = pv
+				found = true
+			}
+		}
+
+		if !found {
+			return dst, false, nil
+		}
+		return d, true, nil
+	case nil:
+		return dst, false, nil
+	default:
+		return dst, false, fmt.Errorf("can't look up %q in a %T", fields[0], src)
+	}
+}
+
+// omit removes the fields at paths from item, elements removed from an array are dropped from it
+func omit(item *unstructured.Unstructured, paths []string) error {
+	remove := func(interface{}) (interface{}, bool) { return nil, false }
+	for _, p := range paths {
+		_, err := updatePath(item.Object, splitPath(p), remove)
+		if err != nil {
+			return fmt.Errorf("omit: path %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// updatePath replaces each value at fields below v with the result of fn, or removes it when fn returns false, and
+// returns v. Missing fields are skipped.
+func updatePath(v interface{}, fields []string, fn func(interface{}) (interface{}, bool)) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		e, ok := t[fields[0]]
+		if !ok {
+			return t, nil
+		}
+
+		if len(fields) == 1 {
+			if u, keep := fn(e); keep {
+				t[fields[0]] = u
+			} else {
+				delete(t, fields[0])
+			}
+			return t, nil
+		}
+
+		u, err := updatePath(e, fields[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		t[fields[0]] = u
+		return t, nil
+	case []interface{}:
+		indexes, err := arrayIndexes(t, fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fields) == 1 {
+			selected := make(map[int]bool, len(indexes))
+			for _, i := range indexes {
+				selected[i] = true
+			}
+
+			kept := make([]interface{}, 0, len(t))
+			for i, e := range t {
+				if !selected[i] {
+					kept = append(kept, e)
+					continue
+				}
+				if u, keep := fn(e); keep {
+					kept = append(kept, u)
+				}
+			}
+			return kept, nil
+		}
+
+		for _, i := range indexes {
+			t[i], err = updatePath(t[i], fields[1:], fn)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("can't look up %q in a %T", fields[0], v)
+	}
+}
+
+// arrayIndexes returns the indexes of the elements of a selected by field, either a numeric index or '#' for every
+// element. An index past the end of a selects nothing.
+func arrayIndexes(a []interface{}, field string) ([]int, error) {
+	if field == "#" {
+		indexes := make([]int, len(a))
+		for i := range a {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	i, err := strconv.Atoi(field)
+	if err != nil || i < 0 {
+		return nil, fmt.Errorf("can't look up %q in an array, use an index or #", field)
+	}
+
+	if i >= len(a) {
+		return nil, nil
+	}
+	return []int{i}, nil
+}
+
+// splitPath splits a gjson style path into its keys and array indexes, dots escaped with '\' are part of a key
+func splitPath(path string) []string {
+	var fields []string
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			b.WriteByte(path[i])
+		case path[i] == '.':
+			fields = append(fields, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(path[i])
+		}
+	}
+	return append(fields, b.String())
+}
@@ -0,0 +1,196 @@
+package k8s
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const podDoc = `{
+	"apiVersion": "v1",
+	"kind": "Pod",
+	"metadata": {
+		"name": "web-0",
+		"namespace": "web",
+		"uid": "7c6f1d5e",
+		"resourceVersion": "42",
+		"annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}"}
+	},
+	"spec": {
+		"containers": [
+			{"name": "app", "image": "web:1", "env": [{"name": "A", "value": "a"}, {"name": "B", "value": "b"}]},
+			{"name": "proxy", "image": "proxy:1"}
+		]
+	},
+	"status": {"phase": "Running"},
+	"_matched": [{"key": "metadata.name", "values": ["web-0"]}]
+}`
+
+// testObject decodes a json document, numbers are float64 as they are in loaded dumps
+func testObject(t *testing.T, s string) map[string]interface{} {
+	var obj map[string]interface{}
+	err := json.Unmarshal([]byte(s), &obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "object keys",
+			paths: []string{"metadata.name", "status.phase", "spec.missing"},
+			want:  `{"metadata": {"name": "web-0"}, "status": {"phase": "Running"}}`,
+		},
+		{
+			name:  "escaped key",
+			paths: []string{`metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`},
+			want:  `{"metadata": {"annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}"}}}`,
+		},
+		{
+			name:  "every element",
+			paths: []string{"spec.containers.#.image"},
+			want:  `{"spec": {"containers": [{"image": "web:1"}, {"image": "proxy:1"}]}}`,
+		},
+		{
+			name:  "index keeps the array's length",
+			paths: []string{"spec.containers.1.name"},
+			want:  `{"spec": {"containers": [null, {"name": "proxy"}]}}`,
+		},
+		{
+			name:  "elements missing the key are null",
+			paths: []string{"spec.containers.#.env.#.value"},
+			want:  `{"spec": {"containers": [{"env": [{"value": "a"}, {"value": "b"}]}, null]}}`,
+		},
+		{
+			name:  "paths are merged",
+			paths: []string{"spec.containers.#.name", "spec.containers.0.image"},
+			want:  `{"spec": {"containers": [{"name": "app", "image": "web:1"}, {"name": "proxy"}]}}`,
+		},
+		{
+			name:  "index past the end",
+			paths: []string{"spec.containers.5"},
+			want:  `{}`,
+		},
+		{
+			name:    "key in an array",
+			paths:   []string{"spec.containers.name"},
+			wantErr: true,
+		},
+		{
+			name:    "key in a string",
+			paths:   []string{"metadata.name.first"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: testObject(t, podDoc)}
+			got, err := project(item, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("project() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want := testObject(t, tt.want); !reflect.DeepEqual(got.Object, want) {
+				t.Errorf("project() = %v, want %v", got.Object, want)
+			}
+			if !reflect.DeepEqual(item.Object, testObject(t, podDoc)) {
+				t.Errorf("project() modified the item")
+			}
+		})
+	}
+}
+
+func TestOmit(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "object keys",
+			paths: []string{"status", "metadata.annotations", "_matched", "spec.missing.key"},
+			want: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-0", "namespace": "web", "uid": "7c6f1d5e", "resourceVersion": "42"},
+				"spec": {"containers": [{"name": "app", "image": "web:1", "env": [{"name": "A", "value": "a"}, {"name": "B", "value": "b"}]},
+				{"name": "proxy", "image": "proxy:1"}]}}`,
+		},
+		{
+			name:  "every element",
+			paths: []string{"metadata", "status", "_matched", "spec.containers.#.env.#.value", "spec.containers.#.image"},
+			want: `{"apiVersion": "v1", "kind": "Pod",
+				"spec": {"containers": [{"name": "app", "env": [{"name": "A"}, {"name": "B"}]}, {"name": "proxy"}]}}`,
+		},
+		{
+			name:  "removed elements are dropped",
+			paths: []string{"metadata", "status", "_matched", "spec.containers.0.env.0", "spec.containers.1"},
+			want: `{"apiVersion": "v1", "kind": "Pod",
+				"spec": {"containers": [{"name": "app", "image": "web:1", "env": [{"name": "B", "value": "b"}]}]}}`,
+		},
+		{
+			name:  "every element removed",
+			paths: []string{"metadata", "status", "_matched", "spec.containers.#"},
+			want:  `{"apiVersion": "v1", "kind": "Pod", "spec": {"containers": []}}`,
+		},
+		{
+			name:    "key in an array",
+			paths:   []string{"spec.containers.image"},
+			wantErr: true,
+		},
+		{
+			name:    "negative index",
+			paths:   []string{"spec.containers.-1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: testObject(t, podDoc)}
+			err := omit(&item, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("omit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want := testObject(t, tt.want); !reflect.DeepEqual(item.Object, want) {
+				t.Errorf("omit() = %v, want %v", item.Object, want)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	item := unstructured.Unstructured{Object: testObject(t, podDoc)}
+	dump := config.Dump{
+		Fields: []string{"metadata", "spec.containers.#.env", "_matched"},
+		Omit:   []string{"spec.containers.#.env.#.name"},
+		Redact: []string{"spec.containers.0.env.1.value"},
+	}
+
+	got, err := prune(item, dump, config.DumpCommand{Clean: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := testObject(t, `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-0", "namespace": "web"},
+		"spec": {"containers": [{"env": [{"value": "a"}, {"value": "REDACTED"}]}, null]}}`)
+	if !reflect.DeepEqual(got.Object, want) {
+		t.Errorf("prune() = %v, want %v", got.Object, want)
+	}
+}
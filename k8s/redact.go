@@ -0,0 +1,242 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// RedactedPlaceholder replaces redacted values in placeholder mode
+	RedactedPlaceholder = "REDACTED"
+)
+
+var (
+	// secretPaths are redacted from Secrets by config.PresetSecrets
+	secretPaths = []string{
+		"data",
+		"stringData",
+		`metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`,
+	}
+
+	// credentialKeys are the lower cased field names redacted at any depth by config.PresetCredentials
+	credentialKeys = map[string]bool{
+		"password":        true,
+		"passwd":          true,
+		"token":           true,
+		"accesstoken":     true,
+		"refreshtoken":    true,
+		"bearertoken":     true,
+		"secret":          true,
+		"secretkey":       true,
+		"secretaccesskey": true,
+		"accesskey":       true,
+		"privatekey":      true,
+		"apikey":          true,
+		"clientsecret":    true,
+		"credentials":     true,
+	}
+)
+
+// redact redacts sensitive values from item according to the global redaction config and the dump's paths
+func redact(item *unstructured.Unstructured, r config.Redaction, paths []string) error {
+	rd, err := newRedactor(r)
+	if err != nil {
+		return err
+	}
+
+	return rd.redact(item, r, paths)
+}
+
+// redactMatches returns a copy of the matches reported for item with every value that's redacted from item redacted
+// the same way. The redaction rules are applied to the whole of item, so values dropped from the output by the dump's
+// fields and omissions are still redacted from the matches.
+func redactMatches(item unstructured.Unstructured, matches []interface{}, dump config.Dump, cfg config.DumpCommand) ([]interface{}, error) {
+	rd, err := newRedactor(cfg.Redact)
+	if err != nil {
+		return nil, err
+	}
+	rd.replaced = make(map[string]interface{})
+
+	err = rd.redact(item.DeepCopy(), cfg.Redact, dump.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make([]interface{}, len(matches))
+	for i, m := range matches {
+		match := runtime.DeepCopyJSONValue(m)
+		if fields, ok := match.(map[string]interface{}); ok {
+			fields["values"] = rd.replace(fields["values"])
+		}
+		redacted[i] = match
+	}
+	return redacted, nil
+}
+
+// redactor replaces sensitive values according to a redaction mode
+type redactor struct {
+	mode string
+	// replaced maps the json encoding of every value redacted, and of the values within it, to its replacement when set
+	replaced map[string]interface{}
+}
+
+func newRedactor(r config.Redaction) (*redactor, error) {
+	mode := strings.ToLower(r.Mode)
+	if mode == "" {
+		mode = config.RedactPlaceholder
+	}
+	if mode != config.RedactPlaceholder && mode != config.RedactHash {
+		return nil, fmt.Errorf("unknown redaction mode: %q, must be %v or %v", r.Mode, config.RedactPlaceholder, config.RedactHash)
+	}
+
+	return &redactor{mode: mode}, nil
+}
+
+// redact redacts the values selected by the presets and paths of r and the dump's paths from item
+func (rd *redactor) redact(item *unstructured.Unstructured, r config.Redaction, paths []string) error {
+	for _, preset := range r.Presets {
+		switch strings.ToLower(preset) {
+		case config.PresetSecrets:
+			gvk := item.GroupVersionKind()
+			if gvk.Group == "" && gvk.Kind == "Secret" {
+				err := rd.redactPaths(item.Object, secretPaths)
+				if err != nil {
+					return err
+				}
+			}
+		case config.PresetCredentials:
+			rd.redactKeys(item.Object)
+		default:
+			return fmt.Errorf("unknown redaction preset: %q, must be %v or %v", preset, config.PresetSecrets, config.PresetCredentials)
+		}
+	}
+
+	err := rd.redactPaths(item.Object, r.Paths)
+	if err != nil {
+		return err
+	}
+
+	return rd.redactPaths(item.Object, paths)
+}
+
+// redactPaths redacts the values at paths, the values of an object at a path are redacted individually so that its keys remain visible
+func (rd *redactor) redactPaths(obj map[string]interface{}, paths []string) error {
+	replace := func(v interface{}) (interface{}, bool) {
+		if m, ok := v.(map[string]interface{}); ok {
+			for k, e := range m {
+				m[k] = rd.redactValue(e)
+			}
+			return m, true
+		}
+		return rd.redactValue(v), true
+	}
+
+	for _, p := range paths {
+		_, err := updatePath(obj, splitPath(p), replace)
+		if err != nil {
+			return fmt.Errorf("redact: path %q: %v", p, err)
+		}
+	}
+
+	return nil
+}
+
+// redactKeys redacts the values of credential fields at any depth of v
+func (rd *redactor) redactKeys(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if credentialKeys[strings.ToLower(k)] {
+				t[k] = rd.redactValue(e)
+				continue
+			}
+			rd.redactKeys(e)
+		}
+	case []interface{}:
+		for _, e := range t {
+			rd.redactKeys(e)
+		}
+	}
+}
+
+// redactValue returns the replacement for a sensitive value
+func (rd *redactor) redactValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	replacement := redactValue(v, rd.mode)
+	if rd.replaced != nil {
+		rd.record(v, replacement)
+	}
+	return replacement
+}
+
+// record records the replacement of v, and the replacements the values within v would have if redacted on their own
+func (rd *redactor) record(v, replacement interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	rd.replaced[string(b)] = replacement
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, e := range t {
+			rd.record(e, redactValue(e, rd.mode))
+		}
+	case []interface{}:
+		for _, e := range t {
+			rd.record(e, redactValue(e, rd.mode))
+		}
+	}
+}
+
+// replace replaces every value within v that was redacted by rd with its replacement and returns the result
+func (rd *redactor) replace(v interface{}) interface{} {
+	if b, err := json.Marshal(v); err == nil {
+		if replacement, ok := rd.replaced[string(b)]; ok {
+			return replacement
+		}
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = rd.replace(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = rd.replace(e)
+		}
+	}
+	return v
+}
+
+// redactValue returns the replacement for a sensitive value
+func redactValue(v interface{}, mode string) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	if mode != config.RedactHash {
+		return RedactedPlaceholder
+	}
+
+	data, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return RedactedPlaceholder
+		}
+		data = string(b)
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(data)))
+}
@@ -0,0 +1,186 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const secretDoc = `{
+	"apiVersion": "v1",
+	"kind": "Secret",
+	"metadata": {"name": "db", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "team": "data"}},
+	"data": {"user": "YWRtaW4=", "password": "aHVudGVyMg=="},
+	"spec": {
+		"auth": {"token": "t0k3n", "tokenTTL": 60, "nested": {"clientSecret": {"a": 1}}},
+		"users": [{"name": "a", "password": "pa"}, {"name": "b", "password": null}]
+	}
+}`
+
+func sha(s string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(s)))
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       config.Redaction
+		paths   []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "secrets preset",
+			r:    config.Redaction{Presets: []string{config.PresetSecrets}},
+			want: `{"apiVersion": "v1", "kind": "Secret",
+				"metadata": {"name": "db", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "REDACTED", "team": "data"}},
+				"data": {"user": "REDACTED", "password": "REDACTED"},
+				"spec": {"auth": {"token": "t0k3n", "tokenTTL": 60, "nested": {"clientSecret": {"a": 1}}},
+					"users": [{"name": "a", "password": "pa"}, {"name": "b", "password": null}]}}`,
+		},
+		{
+			name: "credentials preset",
+			r:    config.Redaction{Presets: []string{"Credentials"}},
+			want: `{"apiVersion": "v1", "kind": "Secret",
+				"metadata": {"name": "db", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "team": "data"}},
+				"data": {"user": "YWRtaW4=", "password": "REDACTED"},
+				"spec": {"auth": {"token": "REDACTED", "tokenTTL": 60, "nested": {"clientSecret": "REDACTED"}},
+					"users": [{"name": "a", "password": "REDACTED"}, {"name": "b", "password": null}]}}`,
+		},
+		{
+			name:  "paths in hash mode",
+			r:     config.Redaction{Mode: config.RedactHash, Paths: []string{"spec.auth.tokenTTL"}},
+			paths: []string{"data", "spec.users.#.name", "spec.auth.nested"},
+			want: `{"apiVersion": "v1", "kind": "Secret",
+				"metadata": {"name": "db", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "team": "data"}},
+				"data": {"user": "` + sha("YWRtaW4=") + `", "password": "` + sha("aHVudGVyMg==") + `"},
+				"spec": {"auth": {"token": "t0k3n", "tokenTTL": "` + sha("60") + `", "nested": {"clientSecret": "` + sha(`{"a":1}`) + `"}},
+					"users": [{"name": "` + sha("a") + `", "password": "pa"}, {"name": "` + sha("b") + `", "password": null}]}}`,
+		},
+		{
+			name:  "index",
+			paths: []string{"spec.users.1.name", "spec.users.2.name", "spec.missing"},
+			want: `{"apiVersion": "v1", "kind": "Secret",
+				"metadata": {"name": "db", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "team": "data"}},
+				"data": {"user": "YWRtaW4=", "password": "aHVudGVyMg=="},
+				"spec": {"auth": {"token": "t0k3n", "tokenTTL": 60, "nested": {"clientSecret": {"a": 1}}},
+					"users": [{"name": "a", "password": "pa"}, {"name": "REDACTED", "password": null}]}}`,
+		},
+		{
+			name:    "key in an array",
+			paths:   []string{"spec.users.name"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown preset",
+			r:       config.Redaction{Presets: []string{"tokens"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			r:       config.Redaction{Mode: "shred"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: testObject(t, secretDoc)}
+			err := redact(&item, tt.r, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("redact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want := testObject(t, tt.want); !reflect.DeepEqual(item.Object, want) {
+				t.Errorf("redact() = %v, want %v", item.Object, want)
+			}
+		})
+	}
+}
+
+func TestRedactMatches(t *testing.T) {
+	match := func(key string, values ...interface{}) interface{} {
+		return map[string]interface{}{"key": key, "values": values}
+	}
+
+	tests := []struct {
+		name    string
+		dump    config.Dump
+		cfg     config.DumpCommand
+		matches []interface{}
+		want    []interface{}
+	}{
+		{
+			name:    "nothing redacted",
+			matches: []interface{}{match("spec.users.#.password", "pa")},
+			want:    []interface{}{match("spec.users.#.password", "pa")},
+		},
+		{
+			name:    "preset",
+			cfg:     config.DumpCommand{Redact: config.Redaction{Presets: []string{config.PresetCredentials}}},
+			matches: []interface{}{match("spec.users.#.password", "pa"), match("spec.users.#.name", "a")},
+			want:    []interface{}{match("spec.users.#.password", "REDACTED"), match("spec.users.#.name", "a")},
+		},
+		{
+			name:    "redacted values within a match",
+			cfg:     config.DumpCommand{Redact: config.Redaction{Mode: config.RedactHash}},
+			dump:    config.Dump{Redact: []string{"spec.auth.nested"}},
+			matches: []interface{}{match("spec.auth", map[string]interface{}{"nested": map[string]interface{}{"clientSecret": map[string]interface{}{"a": float64(1)}}})},
+			want:    []interface{}{match("spec.auth", map[string]interface{}{"nested": map[string]interface{}{"clientSecret": sha(`{"a":1}`)}})},
+		},
+		{
+			name:    "fields that aren't output are still redacted",
+			cfg:     config.DumpCommand{Redact: config.Redaction{Presets: []string{config.PresetSecrets}}},
+			dump:    config.Dump{Fields: []string{"metadata.name"}, Omit: []string{"data"}},
+			matches: []interface{}{match("data", map[string]interface{}{"user": "YWRtaW4="})},
+			want:    []interface{}{match("data", map[string]interface{}{"user": "REDACTED"})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: testObject(t, secretDoc)}
+			got, err := redactMatches(item, tt.matches, tt.dump, tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redactMatches() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(item.Object, testObject(t, secretDoc)) {
+				t.Errorf("redactMatches() modified the item")
+			}
+		})
+	}
+}
+
+func TestFindRedacted(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		wantPath string
+		want     bool
+	}{
+		{name: "none", doc: `{"data": {"user": "admin", "hash": "sha256:abc"}, "spec": {"replicas": 1}}`},
+		{name: "placeholder", doc: `{"data": {"a": "x", "b": "REDACTED"}}`, wantPath: "data.b", want: true},
+		{name: "hash", doc: `{"spec": {"users": [{"password": "x"}, {"password": "` + sha("pa") + `"}]}}`, wantPath: "spec.users.1.password", want: true},
+		{name: "escaped key", doc: `{"metadata": {"annotations": {"a.b/c": "REDACTED"}}}`, wantPath: `metadata.annotations.a\.b/c`, want: true},
+		{name: "first key in order", doc: `{"b": "REDACTED", "a": ["REDACTED"]}`, wantPath: "a.0", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, ok := findRedacted(testObject(t, tt.doc), "")
+			if ok != tt.want || (ok && path != tt.wantPath) {
+				t.Errorf("findRedacted() = %q, %v, want %q, %v", path, ok, tt.wantPath, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// Restore actions
+const (
+	RestoreCreated = "created"
+	RestoreExists  = "exists"
+	RestoreApplied = "applied"
+	RestoreFailed  = "failed"
+	// RestoreSkipped is reported for resources managed by a controller, for resources with redacted values, and in dry
+	// runs for resources in namespaces, or of custom resource definitions, that were only created as part of the dry run,
+	// since the api server can't validate them
+	RestoreSkipped = "skipped"
+)
+
+const (
+	defaultFieldManager = "k8sutil"
+
+	crdPollInterval       = time.Second
+	crdEstablishedTimeout = time.Minute
+)
+
+var (
+	hashRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+	crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+	errDryRunDependency = errors.New("its namespace or custom resource definition only exists in the dry run")
+)
+
+// RestoreOptions configures how resources are restored
+// NamespaceMap renames namespaces, resources in a namespace that isn't mapped keep their namespace
+// ServerSide uses server side apply with FieldManager instead of create, Force takes ownership of conflicting fields
+// DryRun submits requests as server side dry runs so that nothing is persisted
+// AllowRedacted restores resources with redacted values rather than skipping them, writing the redacted values as is
+type RestoreOptions struct {
+	NamespaceMap  map[string]string
+	ServerSide    bool
+	FieldManager  string
+	Force         bool
+	DryRun        bool
+	AllowRedacted bool
+}
+
+// RestoreResult is the outcome of restoring a single resource
+type RestoreResult struct {
+	Item   unstructured.Unstructured
+	Action string
+	Err    error
+}
+
+// Restore creates or applies items in the cluster factory connects to and calls fn with the result for each item.
+// Server populated fields, owner references other than controllers and the cluster ips of Services that aren't headless
+// are removed first. Resources managed by a controller are skipped, since the restored controller recreates them.
+// Namespaces are restored first, then
+// CustomResourceDefinitions, then everything else. Once CustomResourceDefinitions are restored, Restore waits for them
+// to be established and refreshes discovery so that their custom resources can be mapped. In a dry run, resources that
+// need a namespace or CustomResourceDefinition that was only created by the dry run are skipped. Resources with values
+// redacted by a dump are skipped unless opts.AllowRedacted is set, so that placeholders and hashes don't replace live values.
+func Restore(factory *Factory, items []unstructured.Unstructured, opts RestoreOptions, fn func(RestoreResult)) error {
+	if opts.FieldManager == "" {
+		opts.FieldManager = defaultFieldManager
+	}
+
+	prepared := make([]unstructured.Unstructured, len(items))
+	for i, item := range items {
+		prepared[i] = prepareItem(item, opts)
+	}
+
+	sort.SliceStable(prepared, func(i, j int) bool {
+		return restorePriority(prepared[i]) < restorePriority(prepared[j])
+	})
+
+	resolver, err := NewResolver(factory)
+	if err != nil {
+		return err
+	}
+
+	var (
+		// crds holds the names of the CustomResourceDefinitions restored
+		crds []string
+		// dryRunNamespaces and dryRunKinds hold the namespaces and custom resource kinds that were only created as a
+		// dry run, the resources that need them can't be validated by the api server
+		dryRunNamespaces = make(map[string]bool)
+		dryRunKinds      = make(map[schema.GroupKind]bool)
+	)
+	for _, item := range prepared {
+		priority := restorePriority(item)
+		if len(crds) > 0 && priority > crdPriority {
+			// custom resources can only be mapped once their definitions are established and served
+			err = waitEstablished(factory, crds)
+			if err != nil {
+				logrus.Warnf("custom resources may fail to restore: %v", err)
+			}
+
+			crds = nil
+			resolver, err = NewResolver(factory)
+			if err != nil {
+				return err
+			}
+		}
+
+		if controller := metav1.GetControllerOf(&item); controller != nil {
+			err := fmt.Errorf("managed by %v %v", controller.Kind, controller.Name)
+			fn(RestoreResult{Item: item, Action: RestoreSkipped, Err: err})
+			continue
+		}
+
+		if path, ok := findRedacted(item.Object, ""); ok && !opts.AllowRedacted {
+			fn(RestoreResult{Item: item, Action: RestoreSkipped, Err: fmt.Errorf("%v has a redacted value", path)})
+			continue
+		}
+
+		if opts.DryRun && (dryRunNamespaces[item.GetNamespace()] || dryRunKinds[item.GroupVersionKind().GroupKind()]) {
+			fn(RestoreResult{Item: item, Action: RestoreSkipped, Err: errDryRunDependency})
+			continue
+		}
+
+		action, err := restoreItem(factory, resolver, item, opts)
+		switch {
+		case err != nil:
+			logrus.Debugf("failed to restore %v %v/%v: %v", item.GetKind(), item.GetNamespace(), item.GetName(), err)
+			action = RestoreFailed
+		case opts.DryRun && priority < defaultPriority:
+			// a dry run create or apply succeeds whether or not the item exists
+			found, err := itemExists(factory, resolver, item)
+			if err != nil {
+				return err
+			}
+			if found {
+				break
+			}
+
+			if priority == namespacePriority {
+				dryRunNamespaces[item.GetName()] = true
+			} else {
+				group, _, _ := unstructured.NestedString(item.Object, "spec", "group")
+				kind, _, _ := unstructured.NestedString(item.Object, "spec", "names", "kind")
+				dryRunKinds[schema.GroupKind{Group: group, Kind: kind}] = true
+			}
+		case priority == crdPriority:
+			crds = append(crds, item.GetName())
+		}
+
+		fn(RestoreResult{Item: item, Action: action, Err: err})
+	}
+
+	return nil
+}
+
+// prepareItem returns a copy of item that can be created in a cluster. Server populated fields and the owner references
+// that aren't a controller are removed, since the owners' uids won't match, and Services keep their cluster ips only when
+// they're headless, since allocated ips may be taken or outside the cluster's service range. Namespaces are renamed with
+// opts.NamespaceMap.
+func prepareItem(item unstructured.Unstructured, opts RestoreOptions) unstructured.Unstructured {
+	item = *item.DeepCopy()
+	Clean(&item)
+
+	var refs []metav1.OwnerReference
+	if controller := metav1.GetControllerOf(&item); controller != nil {
+		refs = append(refs, *controller)
+	}
+	item.SetOwnerReferences(refs)
+
+	gvk := item.GroupVersionKind()
+	if gvk.Group == "" && gvk.Kind == "Service" {
+		if ip, _, _ := unstructured.NestedString(item.Object, "spec", "clusterIP"); ip != corev1.ClusterIPNone {
+			unstructured.RemoveNestedField(item.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(item.Object, "spec", "clusterIPs")
+		}
+	}
+
+	if ns, ok := opts.NamespaceMap[item.GetNamespace()]; ok {
+		item.SetNamespace(ns)
+	}
+	if ns, ok := opts.NamespaceMap[item.GetName()]; ok && isNamespace(item) {
+		item.SetName(ns)
+	}
+
+	return item
+}
+
+// findRedacted returns the path of the first value of v that was redacted by a dump, either RedactedPlaceholder or a
+// sha256 hash, path is the path of v
+func findRedacted(v interface{}, path string) (string, bool) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if p, ok := findRedacted(t[k], join(strings.ReplaceAll(k, ".", `\.`))); ok {
+				return p, true
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			if p, ok := findRedacted(e, join(strconv.Itoa(i))); ok {
+				return p, true
+			}
+		}
+	case string:
+		return path, t == RedactedPlaceholder || hashRegex.MatchString(t)
+	}
+
+	return "", false
+}
+
+// waitEstablished waits until the CustomResourceDefinitions named are established or crdEstablishedTimeout passes
+func waitEstablished(factory *Factory, names []string) error {
+	cli, err := factory.DynamicClient()
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("waiting for %v CustomResourceDefinitions to be established", len(names))
+	return wait.PollImmediate(crdPollInterval, crdEstablishedTimeout, func() (bool, error) {
+		for _, name := range names {
+			crd, err := cli.Resource(crdGVR).Get(context.TODO(), name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+
+			if !isEstablished(*crd) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// isEstablished reports whether a CustomResourceDefinition has the Established condition
+func isEstablished(crd unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Established" && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// itemClient returns a dynamic client for item's resource in its namespace, namespaced items without a namespace use
+// the factory's namespace
+func itemClient(factory *Factory, resolver *Resolver, item unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := item.GroupVersionKind()
+	mapping, err := resolver.Mapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := item.GetNamespace()
+	if ns == "" && mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ns, err = factory.Namespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return factory.ResourceClient(mapping, ns)
+}
+
+// itemExists reports whether item exists in the cluster
+func itemExists(factory *Factory, resolver *Resolver, item unstructured.Unstructured) (bool, error) {
+	cli, err := itemClient(factory, resolver, item)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = cli.Get(context.TODO(), item.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// restoreItem creates or applies a single item
+func restoreItem(factory *Factory, resolver *Resolver, item unstructured.Unstructured, opts RestoreOptions) (string, error) {
+	cli, err := itemClient(factory, resolver, item)
+	if err != nil {
+		return "", err
+	}
+
+	var dryRun []string
+	if opts.DryRun {
+		dryRun = []string{metav1.DryRunAll}
+	}
+
+	if opts.ServerSide {
+		data, err := json.Marshal(item.Object)
+		if err != nil {
+			return "", err
+		}
+
+		force := opts.Force
+		_, err = cli.Patch(context.TODO(), item.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			DryRun:       dryRun,
+			FieldManager: opts.FieldManager,
+			Force:        &force,
+		})
+		if err != nil {
+			return "", err
+		}
+		return RestoreApplied, nil
+	}
+
+	_, err = cli.Create(context.TODO(), &item, metav1.CreateOptions{
+		DryRun:       dryRun,
+		FieldManager: opts.FieldManager,
+	})
+	if apierrors.IsAlreadyExists(err) {
+		return RestoreExists, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return RestoreCreated, nil
+}
+
+const (
+	namespacePriority = iota
+	crdPriority
+	defaultPriority
+)
+
+// restorePriority orders namespaces before CustomResourceDefinitions before everything else
+func restorePriority(item unstructured.Unstructured) int {
+	switch {
+	case isNamespace(item):
+		return namespacePriority
+	case item.GroupVersionKind().GroupKind().String() == "CustomResourceDefinition.apiextensions.k8s.io":
+		return crdPriority
+	default:
+		return defaultPriority
+	}
+}
+
+func isNamespace(item unstructured.Unstructured) bool {
+	gvk := item.GroupVersionKind()
+	return gvk.Group == "" && gvk.Kind == "Namespace"
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRestorePriority(t *testing.T) {
+	item := func(apiVersion, kind, name string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": name},
+		}}
+	}
+
+	items := []unstructured.Unstructured{
+		item("v1", "ConfigMap", "a"),
+		item("example.com/v1", "Widget", "b"),
+		item("apiextensions.k8s.io/v1", "CustomResourceDefinition", "widgets.example.com"),
+		item("v1", "Namespace", "web"),
+		item("example.com/v1", "Namespace", "c"),
+		item("apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "gadgets.example.com"),
+		item("v1", "Namespace", "db"),
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return restorePriority(items[i]) < restorePriority(items[j])
+	})
+
+	var got []string
+	for _, i := range items {
+		got = append(got, i.GetName())
+	}
+
+	want := []string{"web", "db", "widgets.example.com", "gadgets.example.com", "a", "b", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restore order = %v, want %v", got, want)
+	}
+}
+
+func TestPrepareItem(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RestoreOptions
+		item string
+		want string
+	}{
+		{
+			name: "server fields and owner references that aren't controllers",
+			item: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "web", "uid": "1",
+				"resourceVersion": "2", "ownerReferences": [{"apiVersion": "v1", "kind": "ConfigMap", "name": "b", "uid": "3"}]}}`,
+			want: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "web"}}`,
+		},
+		{
+			name: "controller owner reference",
+			item: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-0", "ownerReferences": [
+				{"apiVersion": "v1", "kind": "ConfigMap", "name": "b", "uid": "3"},
+				{"apiVersion": "apps/v1", "kind": "StatefulSet", "name": "web", "uid": "4", "controller": true}]}}`,
+			want: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-0", "ownerReferences": [
+				{"apiVersion": "apps/v1", "kind": "StatefulSet", "name": "web", "uid": "4", "controller": true}]}}`,
+		},
+		{
+			name: "service cluster ips",
+			item: `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"},
+				"spec": {"clusterIP": "10.43.0.10", "clusterIPs": ["10.43.0.10"], "ports": [{"port": 80}]}}`,
+			want: `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}, "spec": {"ports": [{"port": 80}]}}`,
+		},
+		{
+			name: "headless service",
+			item: `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}, "spec": {"clusterIP": "None", "clusterIPs": ["None"]}}`,
+			want: `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}, "spec": {"clusterIP": "None", "clusterIPs": ["None"]}}`,
+		},
+		{
+			name: "cluster ip of another kind",
+			item: `{"apiVersion": "example.com/v1", "kind": "Service", "metadata": {"name": "web"}, "spec": {"clusterIP": "10.43.0.10"}}`,
+			want: `{"apiVersion": "example.com/v1", "kind": "Service", "metadata": {"name": "web"}, "spec": {"clusterIP": "10.43.0.10"}}`,
+		},
+		{
+			name: "namespace map",
+			opts: RestoreOptions{NamespaceMap: map[string]string{"web": "web-restored"}},
+			item: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web", "namespace": "web"}}`,
+			want: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web", "namespace": "web-restored"}}`,
+		},
+		{
+			name: "mapped namespace",
+			opts: RestoreOptions{NamespaceMap: map[string]string{"web": "web-restored"}},
+			item: `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "web"}}`,
+			want: `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "web-restored"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: testObject(t, tt.item)}
+			got := prepareItem(item, tt.opts)
+			want := testObject(t, tt.want)
+			if !reflect.DeepEqual(got.Object, want) {
+				t.Errorf("prepareItem() = %v, want %v", got.Object, want)
+			}
+			if !reflect.DeepEqual(item.Object, testObject(t, tt.item)) {
+				t.Errorf("prepareItem() modified the item")
+			}
+		})
+	}
+}
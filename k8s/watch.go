@@ -0,0 +1,289 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ryansann/k8sutil/config"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// WatchEvent is a change to the set of resources satisfying a dump
+type WatchEvent struct {
+	Time   time.Time              `json:"time"`
+	Type   watch.EventType        `json:"type"`
+	Key    string                 `json:"key"`
+	Object map[string]interface{} `json:"object"`
+}
+
+// WatchDumps lists each dump's resources and then watches them until ctx is done, calling fn for every change.
+// Resources satisfying a dump's filters when first listed are reported as ADDED. Afterwards, resources that start
+// satisfying the filters are reported as ADDED, ones that stop satisfying them or are deleted as DELETED, and changes
+// to ones that continue to satisfy them as MODIFIED. Calls to fn are serialized.
+func WatchDumps(ctx context.Context, factory *Factory, cfg config.DumpCommand, fn func(WatchEvent) error) error {
+	targets, err := resolveTargets(factory, cfg)
+	if err != nil {
+		return err
+	}
+
+	// joined values are resolved once from the dumps' initial results
+	targets, err = resolveJoins(factory, cfg, targets)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		firstErr error
+	)
+
+	emit := func(e WatchEvent) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return fn(e)
+	}
+
+	fail := func(err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	for _, t := range targets {
+		namespaces, err := targetNamespaces(factory, t)
+		if err != nil {
+			return err
+		}
+
+		for _, ns := range namespaces {
+			cli, err := factory.ResourceClient(t.mapping, ns)
+			if err != nil {
+				return err
+			}
+
+			watchCli, err := factory.WatchResourceClient(t.mapping, ns)
+			if err != nil {
+				return err
+			}
+
+			w := &dumpWatcher{
+				cli:      cli,
+				watchCli: watchCli,
+				dump:     t.dump,
+				key:      t.dump.Key(),
+				cfg:      cfg,
+				emit:     emit,
+				matched:  make(map[types.UID]unstructured.Unstructured),
+			}
+
+			wg.Add(1)
+			go func(t dumpTarget) {
+				defer wg.Done()
+				err := w.run(ctx)
+				if err != nil && t.discovered {
+					logrus.Warnf("skipping %v: %v", ResourceString(t.mapping.Resource), err)
+					return
+				}
+				if err != nil {
+					fail(err)
+				}
+			}(t)
+		}
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// dumpWatcher tracks the resources satisfying a dump in a single namespace
+type dumpWatcher struct {
+	cli dynamic.ResourceInterface
+	// watchCli is used for watches, its requests have no timeout
+	watchCli dynamic.ResourceInterface
+	dump     config.Dump
+	key      string
+	cfg      config.DumpCommand
+	emit     func(WatchEvent) error
+	// matched holds the last seen state of every resource satisfying the dump's filters
+	matched map[types.UID]unstructured.Unstructured
+}
+
+// run lists and watches until ctx is done, the resources are listed again if the watch expires
+func (w *dumpWatcher) run(ctx context.Context) error {
+	for {
+		rv, err := w.sync()
+		if err != nil {
+			return err
+		}
+
+		expired, err := w.watch(ctx, rv)
+		if err != nil || !expired {
+			return err
+		}
+
+		logrus.Debugf("watch for %v expired, listing again", w.key)
+	}
+}
+
+// sync lists the dump's resources and reports the differences from the last known state,
+// it returns the resource version to start watching from
+func (w *dumpWatcher) sync() (string, error) {
+	opts := metav1.ListOptions{
+		LabelSelector: w.dump.LabelSelector,
+		FieldSelector: w.dump.FieldSelector,
+		Limit:         w.cfg.PageSize,
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+
+	var rv string
+	listed := make(map[types.UID]bool)
+	err := listPages(w.cli, opts, func(l *unstructured.UnstructuredList) error {
+		rv = l.GetResourceVersion()
+		for _, item := range l.Items {
+			listed[item.GetUID()] = true
+			err := w.handle(watch.Modified, item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for uid, item := range w.matched {
+		if !listed[uid] {
+			err = w.handle(watch.Deleted, item)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return rv, nil
+}
+
+// watch reports changes from resource version rv until ctx is done or the watch expires
+func (w *dumpWatcher) watch(ctx context.Context, rv string) (expired bool, err error) {
+	lw := &cache.ListWatch{
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = w.dump.LabelSelector
+			opts.FieldSelector = w.dump.FieldSelector
+			return w.watchCli.Watch(ctx, opts)
+		},
+	}
+
+	rw, err := watchtools.NewRetryWatcher(rv, lw)
+	if err != nil {
+		return false, err
+	}
+	defer rw.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case e, ok := <-rw.ResultChan():
+			if !ok {
+				return true, nil
+			}
+
+			switch e.Type {
+			case watch.Added, watch.Modified, watch.Deleted:
+				item, ok := e.Object.(*unstructured.Unstructured)
+				if !ok {
+					return false, fmt.Errorf("unexpected object in watch event: %T", e.Object)
+				}
+
+				err = w.handle(e.Type, *item)
+				if err != nil {
+					return false, err
+				}
+			case watch.Error:
+				err := apierrors.FromObject(e.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					return true, nil
+				}
+				return false, err
+			}
+		}
+	}
+}
+
+// handle updates the matched state for a change to item and emits the resulting event, if any
+func (w *dumpWatcher) handle(t watch.EventType, item unstructured.Unstructured) error {
+	uid := item.GetUID()
+	last, wasMatched := w.matched[uid]
+
+	isMatched := false
+	r := &matchReport{}
+	if t != watch.Deleted {
+		m, err := matches(item, w.dump.Filters, r)
+		if err != nil {
+			return err
+		}
+		isMatched = m
+	}
+
+	var et watch.EventType
+	switch {
+	case isMatched && !wasMatched:
+		et = watch.Added
+	case isMatched && wasMatched:
+		if last.GetResourceVersion() == item.GetResourceVersion() {
+			return nil
+		}
+		et = watch.Modified
+	case !isMatched && wasMatched:
+		et = watch.Deleted
+	default:
+		return nil
+	}
+
+	if isMatched {
+		w.matched[uid] = item
+	} else {
+		delete(w.matched, uid)
+	}
+
+	pruned, err := prune(*item.DeepCopy(), w.dump, w.cfg)
+	if err != nil {
+		return err
+	}
+
+	if w.dump.ReportMatches && len(r.matches) > 0 {
+		matched, err := redactMatches(item, r.matches, w.dump, w.cfg)
+		if err != nil {
+			return err
+		}
+		pruned.Object[MatchedField] = matched
+	}
+
+	return w.emit(WatchEvent{
+		Time:   time.Now().UTC(),
+		Type:   et,
+		Key:    w.key,
+		Object: pruned.Object,
+	})
+}
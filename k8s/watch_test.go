@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestDumpWatcherHandle(t *testing.T) {
+	pod := func(uid, rv, phase string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": "pod-" + uid, "uid": uid, "resourceVersion": rv},
+			"status":     map[string]interface{}{"phase": phase},
+		}}
+	}
+
+	type change struct {
+		t    watch.EventType
+		item unstructured.Unstructured
+	}
+
+	tests := []struct {
+		name    string
+		changes []change
+		want    []watch.EventType
+	}{
+		{
+			name:    "added when listed",
+			changes: []change{{watch.Modified, pod("a", "1", "Failed")}, {watch.Modified, pod("b", "1", "Running")}},
+			want:    []watch.EventType{watch.Added},
+		},
+		{
+			name:    "unchanged resources aren't reported again",
+			changes: []change{{watch.Added, pod("a", "1", "Failed")}, {watch.Modified, pod("a", "1", "Failed")}},
+			want:    []watch.EventType{watch.Added},
+		},
+		{
+			name:    "modified",
+			changes: []change{{watch.Added, pod("a", "1", "Failed")}, {watch.Modified, pod("a", "2", "Failed")}},
+			want:    []watch.EventType{watch.Added, watch.Modified},
+		},
+		{
+			name:    "starts matching",
+			changes: []change{{watch.Added, pod("a", "1", "Running")}, {watch.Modified, pod("a", "2", "Failed")}},
+			want:    []watch.EventType{watch.Added},
+		},
+		{
+			name:    "stops matching",
+			changes: []change{{watch.Added, pod("a", "1", "Failed")}, {watch.Modified, pod("a", "2", "Running")}},
+			want:    []watch.EventType{watch.Added, watch.Deleted},
+		},
+		{
+			name: "deleted",
+			changes: []change{
+				{watch.Added, pod("a", "1", "Failed")},
+				{watch.Deleted, pod("a", "2", "Failed")},
+				{watch.Deleted, pod("b", "1", "Failed")},
+			},
+			want: []watch.EventType{watch.Added, watch.Deleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []watch.EventType
+			w := &dumpWatcher{
+				dump: config.Dump{Filters: config.Filter{FilterElement: config.FilterElement{Key: "status.phase", Value: "Failed"}}},
+				key:  "pods",
+				emit: func(e WatchEvent) error {
+					if e.Key != "pods" {
+						t.Errorf("event key = %q, want pods", e.Key)
+					}
+					got = append(got, e.Type)
+					return nil
+				},
+				matched: make(map[types.UID]unstructured.Unstructured),
+			}
+
+			for _, c := range tt.changes {
+				err := w.handle(c.t, c.item)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package output
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	manifestFile = "manifest.json"
+	summaryFile  = "summary.yaml"
+
+	// clusterScoped is used in place of a namespace for cluster scoped resources, it can never be a valid namespace name
+	clusterScoped = "_cluster"
+	// coreGroup is used in place of the empty group of core resources
+	coreGroup = "core"
+
+	// bundles may hold secrets, so only the user that wrote them can read them
+	bundleDirMode  = 0700
+	bundleFileMode = 0600
+)
+
+// fileWriter stores the files of a bundle
+type fileWriter interface {
+	WriteFile(name string, data []byte) error
+	Close() error
+}
+
+// Bundle writes every dumped item to its own file laid out as <group>/<version>/<resource>/<namespace>/<name>.yaml,
+// alongside a manifest indexing every file and a summary of the dump
+type Bundle struct {
+	files   fileWriter
+	written map[string]bool
+	entries []ManifestEntry
+	summary Summary
+}
+
+// ManifestEntry indexes a single file in a bundle
+type ManifestEntry struct {
+	Path      string `json:"path"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// Summary describes how a bundle was produced
+type Summary struct {
+	Context string        `json:"context"`
+	Created time.Time     `json:"created"`
+	Dumps   []DumpSummary `json:"dumps"`
+	index   map[string]int
+}
+
+// DumpSummary is the number of items written for a dump entry
+type DumpSummary struct {
+	Key       string `json:"key"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Count     int    `json:"count"`
+}
+
+// NewDirBundle returns a Bundle that writes files under dir, context is recorded in the summary
+func NewDirBundle(dir string, context string) (*Bundle, error) {
+	err := os.MkdirAll(dir, bundleDirMode)
+	if err != nil {
+		return nil, err
+	}
+	return newBundle(dirWriter(dir), context), nil
+}
+
+// NewTarBundle returns a Bundle that writes files to a gzipped tarball at file, context is recorded in the summary
+func NewTarBundle(file string, context string) (*Bundle, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, bundleFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(f)
+	return newBundle(&tarWriter{f: f, gz: gz, tw: tar.NewWriter(gz)}, context), nil
+}
+
+func newBundle(files fileWriter, context string) *Bundle {
+	return &Bundle{
+		files:   files,
+		written: make(map[string]bool),
+		summary: Summary{
+			Context: context,
+			Created: time.Now().UTC(),
+			index:   make(map[string]int),
+		},
+	}
+}
+
+// Begin adds a dump to the summary
+func (b *Bundle) Begin(key string, dump config.Dump) error {
+	b.summary.index[key] = len(b.summary.Dumps)
+	b.summary.Dumps = append(b.summary.Dumps, DumpSummary{
+		Key:       key,
+		Group:     dump.GVR.Group,
+		Version:   dump.GVR.Version,
+		Resource:  dump.GVR.Resource,
+		Namespace: dump.Namespace,
+	})
+	return nil
+}
+
+// Write writes an item to its own file, items selected by more than one dump are only written once
+func (b *Bundle) Write(key string, item unstructured.Unstructured) error {
+	ds := &b.summary.Dumps[b.summary.index[key]]
+	ds.Count++
+
+	ns := item.GetNamespace()
+	if ns == "" {
+		ns = clusterScoped
+	}
+
+	group := ds.Group
+	if group == "" {
+		group = coreGroup
+	}
+
+	p := path.Join(group, ds.Version, ds.Resource, ns, item.GetName()+".yaml")
+	if b.written[p] {
+		return nil
+	}
+	b.written[p] = true
+
+	data, err := yaml.Marshal(item.Object)
+	if err != nil {
+		return err
+	}
+
+	b.entries = append(b.entries, ManifestEntry{
+		Path:      p,
+		Group:     ds.Group,
+		Version:   ds.Version,
+		Resource:  ds.Resource,
+		Namespace: item.GetNamespace(),
+		Name:      item.GetName(),
+	})
+
+	return b.files.WriteFile(p, data)
+}
+
+// Flush writes the manifest and summary and closes the bundle
+func (b *Bundle) Flush() error {
+	manifest, err := json.MarshalIndent(b.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = b.files.WriteFile(manifestFile, manifest)
+	if err != nil {
+		return err
+	}
+
+	summary, err := yaml.Marshal(b.summary)
+	if err != nil {
+		return err
+	}
+
+	err = b.files.WriteFile(summaryFile, summary)
+	if err != nil {
+		return err
+	}
+
+	return b.files.Close()
+}
+
+// dirWriter writes files relative to a directory
+type dirWriter string
+
+func (d dirWriter) WriteFile(name string, data []byte) error {
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+
+	err := os.MkdirAll(filepath.Dir(p), bundleDirMode)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, data, bundleFileMode)
+}
+
+func (d dirWriter) Close() error {
+	return nil
+}
+
+// tarWriter writes files to a gzipped tarball
+type tarWriter struct {
+	f  *os.File
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (t *tarWriter) WriteFile(name string, data []byte) error {
+	err := t.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    bundleFileMode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.tw.Write(data)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	err := t.tw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = t.gz.Close()
+	if err != nil {
+		return err
+	}
+
+	return t.f.Close()
+}
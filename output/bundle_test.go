@@ -0,0 +1,77 @@
+package output
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundlePermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bundle")
+	b, err := NewDirBundle(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	printDumps(t, b, testDumps(t))
+
+	err = filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		want := os.FileMode(bundleFileMode)
+		if fi.IsDir() {
+			want = os.ModeDir | bundleDirMode
+		}
+		if fi.Mode() != want {
+			t.Errorf("%v has mode %v, want %v", p, fi.Mode(), want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	b, err = NewTarBundle(file, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	printDumps(t, b, testDumps(t))
+
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != bundleFileMode {
+		t.Errorf("%v has mode %v, want %v", file, fi.Mode(), os.FileMode(bundleFileMode))
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Mode != bundleFileMode {
+			t.Errorf("%v has mode %o, want %o", hdr.Name, hdr.Mode, bundleFileMode)
+		}
+	}
+}
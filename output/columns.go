@@ -0,0 +1,153 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ryansann/k8sutil/config"
+	"github.com/tidwall/gjson"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// column computes a single value of a row for an item
+type column struct {
+	name  string
+	value func(item unstructured.Unstructured) (string, error)
+}
+
+// templateFuncs are available to column templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	// join joins the elements of a list with sep
+	"join": func(sep string, v interface{}) string {
+		l, ok := v.([]interface{})
+		if !ok {
+			return fmt.Sprint(v)
+		}
+
+		elts := make([]string, len(l))
+		for i, e := range l {
+			elts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(elts, sep)
+	},
+	// gjson returns the value at a gjson path of v
+	"gjson": func(v interface{}, path string) (interface{}, error) {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return gjson.GetBytes(raw, path).Value(), nil
+	},
+}
+
+// columnsFor returns the columns configured for a dump, or the default namespace, name and age columns
+func columnsFor(dump config.Dump) ([]column, error) {
+	if len(dump.Columns) == 0 {
+		return defaultColumns(dump), nil
+	}
+
+	cols := make([]column, len(dump.Columns))
+	for i, c := range dump.Columns {
+		col, err := newColumn(c)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+func newColumn(c config.Column) (column, error) {
+	name := c.Name
+	if name == "" {
+		name = c.Path
+	}
+
+	switch {
+	case c.Template != "":
+		tmpl, err := template.New(name).Funcs(templateFuncs).Parse(c.Template)
+		if err != nil {
+			return column{}, fmt.Errorf("invalid template for column %q: %v", name, err)
+		}
+
+		return column{name: name, value: func(item unstructured.Unstructured) (string, error) {
+			var b bytes.Buffer
+			err := tmpl.Execute(&b, item.Object)
+			if err != nil {
+				return "", err
+			}
+			return strings.ReplaceAll(b.String(), "<no value>", ""), nil
+		}}, nil
+	case c.Path != "":
+		return column{name: name, value: func(item unstructured.Unstructured) (string, error) {
+			raw, err := json.Marshal(item.Object)
+			if err != nil {
+				return "", err
+			}
+
+			result := gjson.GetBytes(raw, c.Path)
+			if !result.IsArray() {
+				return result.String(), nil
+			}
+
+			var elts []string
+			for _, e := range result.Array() {
+				elts = append(elts, e.String())
+			}
+			return strings.Join(elts, ","), nil
+		}}, nil
+	default:
+		return column{}, fmt.Errorf("column %q must set path or template", c.Name)
+	}
+}
+
+// defaultColumns are similar to those of kubectl get when it prints more than one resource type
+func defaultColumns(dump config.Dump) []column {
+	resource := dump.GVR.Resource
+	if dump.GVR.Group != "" {
+		resource = strings.Join([]string{dump.GVR.Resource, dump.GVR.Group}, ".")
+	}
+
+	return []column{
+		{name: "NAMESPACE", value: func(item unstructured.Unstructured) (string, error) {
+			return item.GetNamespace(), nil
+		}},
+		{name: "NAME", value: func(item unstructured.Unstructured) (string, error) {
+			return resource + "/" + item.GetName(), nil
+		}},
+		{name: "AGE", value: func(item unstructured.Unstructured) (string, error) {
+			ts := item.GetCreationTimestamp()
+			if ts.IsZero() {
+				return "<unknown>", nil
+			}
+			return duration.HumanDuration(time.Since(ts.Time)), nil
+		}},
+	}
+}
+
+// row returns the values of cols for item
+func row(cols []column, item unstructured.Unstructured) ([]string, error) {
+	values := make([]string, len(cols))
+	for i, c := range cols {
+		v, err := c.value(item)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %v", c.name, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// header returns the names of cols
+func header(cols []column) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	return names
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CSV prints a row per item with the columns of its dump, a header row is printed whenever the columns change
+type CSV struct {
+	w      *csv.Writer
+	cols   []column
+	header string
+}
+
+// NewCSV returns a CSV printer that writes to w
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+// Begin prints the header row for a dump unless it's the same as the previous dump's
+func (p *CSV) Begin(key string, dump config.Dump) error {
+	cols, err := columnsFor(dump)
+	if err != nil {
+		return err
+	}
+	p.cols = cols
+
+	names := header(cols)
+	if h := strings.Join(names, "\x00"); h != p.header {
+		p.header = h
+		return p.w.Write(names)
+	}
+	return nil
+}
+
+// Write prints a row for an item
+func (p *CSV) Write(key string, item unstructured.Unstructured) error {
+	values, err := row(p.cols, item)
+	if err != nil {
+		return err
+	}
+
+	err = p.w.Write(values)
+	if err != nil {
+		return err
+	}
+
+	// flush every row so that output is streamed
+	p.w.Flush()
+	return p.w.Error()
+}
+
+// Flush flushes any buffered rows
+func (p *CSV) Flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
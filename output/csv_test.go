@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCSV(t *testing.T) {
+	deploy := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "a", "labels": map[string]interface{}{"app": "web"}},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "web:1"},
+				map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+			}}},
+		},
+	}}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	tests := []struct {
+		name    string
+		dumps   []config.Dump
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "default columns",
+			dumps: []config.Dump{{GVR: deployments}},
+			want:  "NAMESPACE,NAME,AGE\na,deployments.apps/web,<unknown>\n",
+		},
+		{
+			name: "paths",
+			dumps: []config.Dump{{GVR: deployments, Columns: []config.Column{
+				{Name: "NAME", Path: "metadata.name"},
+				{Path: "spec.replicas"},
+				{Name: "IMAGES", Path: "spec.template.spec.containers.#.image"},
+				{Name: "MISSING", Path: "status.readyReplicas"},
+			}}},
+			want: "NAME,spec.replicas,IMAGES,MISSING\nweb,2,\"web:1,proxy:1\",\n",
+		},
+		{
+			name: "templates",
+			dumps: []config.Dump{{GVR: deployments, Columns: []config.Column{
+				{Name: "APP", Template: "{{.metadata.labels.app}}/{{.metadata.labels.missing}}"},
+				{Name: "CONTAINERS", Template: `{{join ";" (gjson . "spec.template.spec.containers.#.name")}}`},
+			}}},
+			want: "APP,CONTAINERS\nweb/,app;proxy\n",
+		},
+		{
+			name: "header is only repeated when the columns change",
+			dumps: []config.Dump{
+				{GVR: deployments, Columns: []config.Column{{Name: "NAME", Path: "metadata.name"}}},
+				{Name: "again", GVR: deployments, Columns: []config.Column{{Name: "NAME", Path: "metadata.name"}}},
+				{Name: "other", GVR: deployments, Columns: []config.Column{{Name: "NS", Path: "metadata.namespace"}}},
+			},
+			want: "NAME\nweb\nweb\nNS\na\n",
+		},
+		{
+			name:    "invalid template",
+			dumps:   []config.Dump{{GVR: deployments, Columns: []config.Column{{Name: "A", Template: "{{.metadata"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty column",
+			dumps:   []config.Dump{{GVR: deployments, Columns: []config.Column{{Name: "A"}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := NewCSV(&buf)
+
+			var err error
+			for _, d := range tt.dumps {
+				err = p.Begin(d.Key(), d)
+				if err != nil {
+					break
+				}
+				err = p.Write(d.Key(), deploy)
+				if err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = p.Flush()
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
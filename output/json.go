@@ -0,0 +1,75 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// JSON prints dumps as a single indented json object keyed by dump
+type JSON struct {
+	w       io.Writer
+	started bool
+	empty   bool
+}
+
+// NewJSON returns a JSON printer that writes to w
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{w: w}
+}
+
+// Begin opens the json array for a dump
+func (p *JSON) Begin(key string, dump config.Dump) error {
+	prefix := "{\n"
+	if p.started {
+		prefix = p.closeArray() + ",\n"
+	}
+	p.started = true
+	p.empty = true
+
+	k, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(p.w, "%s  %s: [", prefix, k)
+	return err
+}
+
+// Write appends an item to the current dump's array
+func (p *JSON) Write(key string, item unstructured.Unstructured) error {
+	b, err := json.MarshalIndent(item.Object, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := ","
+	if p.empty {
+		sep = ""
+	}
+	p.empty = false
+
+	_, err = fmt.Fprintf(p.w, "%s\n    %s", sep, b)
+	return err
+}
+
+// Flush closes the json object
+func (p *JSON) Flush() error {
+	if !p.started {
+		_, err := fmt.Fprintln(p.w, "{}")
+		return err
+	}
+
+	_, err := fmt.Fprintf(p.w, "%s\n}\n", p.closeArray())
+	return err
+}
+
+func (p *JSON) closeArray() string {
+	if p.empty {
+		return "]"
+	}
+	return "\n  ]"
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// List prints the items of every dump as a single kubernetes v1.List
+// the output can be used with kubectl apply -f or deduperbs --input-file-rbs / --input-file-crbs
+type List struct {
+	w     io.Writer
+	empty bool
+}
+
+// NewList returns a List printer that writes to w
+func NewList(w io.Writer) *List {
+	return &List{w: w, empty: true}
+}
+
+// Begin is a no-op, items from every dump share a single list
+func (p *List) Begin(key string, dump config.Dump) error {
+	return nil
+}
+
+// Write appends an item to the list
+func (p *List) Write(key string, item unstructured.Unstructured) error {
+	b, err := json.MarshalIndent(item.Object, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	prefix := ","
+	if p.empty {
+		prefix = "{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"List\",\n  \"items\": ["
+	}
+	p.empty = false
+
+	_, err = fmt.Fprintf(p.w, "%s\n    %s", prefix, b)
+	return err
+}
+
+// Flush closes the list
+func (p *List) Flush() error {
+	if p.empty {
+		_, err := fmt.Fprintln(p.w, "{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"List\",\n  \"items\": []\n}")
+		return err
+	}
+
+	_, err := fmt.Fprintln(p.w, "\n  ]\n}")
+	return err
+}
@@ -0,0 +1,231 @@
+package output
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Load reads the resources from dump output at path. Path may be a bundle directory or gzipped tarball,
+// or a file printed in the json, yaml, ndjson or list format.
+func Load(path string) ([]unstructured.Unstructured, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return loadDir(path)
+	}
+
+	if strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") {
+		return loadTar(path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not load %v: %v", path, err)
+	}
+
+	return items, nil
+}
+
+// Decode returns the resources in data printed in the json, yaml, ndjson or list format, or a single resource
+func Decode(data []byte) ([]unstructured.Unstructured, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	// ndjson lines are each a complete json object while json output spans multiple lines, a single line is ndjson
+	// when it wraps its resource and is otherwise decoded as json below
+	first := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		first = data[:i]
+	}
+	if json.Valid(first) && (len(first) < len(data) || wrapsObject(first)) {
+		return decodeNDJSON(data)
+	}
+
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single resource or a v1.List
+	if _, ok := doc["apiVersion"]; ok {
+		if items, ok := doc["items"].([]interface{}); ok && strings.HasSuffix(fmt.Sprint(doc["kind"]), "List") {
+			return toItems(items)
+		}
+		return []unstructured.Unstructured{{Object: doc}}, nil
+	}
+
+	// a map of dump key to resources
+	var items []unstructured.Unstructured
+	for key, v := range doc {
+		l, ok := v.([]interface{})
+		if !ok && v != nil {
+			return nil, fmt.Errorf("dump %q is not a list of resources", key)
+		}
+
+		dumped, err := toItems(l)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, dumped...)
+	}
+
+	return items, nil
+}
+
+// decodeNDJSON returns the resource on each line, either wrapped with the key of its dump or on its own
+func decodeNDJSON(data []byte) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; s.Scan(); n++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+
+		var line map[string]interface{}
+		err := json.Unmarshal(s.Bytes(), &line)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", n, err)
+		}
+
+		obj, ok := line["object"].(map[string]interface{})
+		if !ok || !isResource(obj) {
+			obj = line
+		}
+		if !isResource(obj) {
+			return nil, fmt.Errorf("line %v: not a resource, expected apiVersion and kind", n)
+		}
+		items = append(items, unstructured.Unstructured{Object: obj})
+	}
+	return items, s.Err()
+}
+
+// wrapsObject reports whether line is an ndjson line wrapping a resource with the key of its dump
+func wrapsObject(line []byte) bool {
+	var l ndjsonLine
+	return json.Unmarshal(line, &l) == nil && isResource(l.Object)
+}
+
+// isResource reports whether obj has the apiVersion and kind of a resource
+func isResource(obj map[string]interface{}) bool {
+	_, hasVersion := obj["apiVersion"].(string)
+	_, hasKind := obj["kind"].(string)
+	return hasVersion && hasKind
+}
+
+func toItems(l []interface{}) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	for _, v := range l {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected %T in list of resources", v)
+		}
+		items = append(items, unstructured.Unstructured{Object: obj})
+	}
+	return items, nil
+}
+
+// isBundleFile reports whether name is a resource file in a bundle rather than its manifest or summary
+func isBundleFile(name string) bool {
+	base := filepath.Base(name)
+	return strings.HasSuffix(base, ".yaml") && base != summaryFile && base != manifestFile
+}
+
+func loadDir(dir string) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || !isBundleFile(p) {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		item, err := decodeObject(data)
+		if err != nil {
+			return fmt.Errorf("could not load %v: %v", p, err)
+		}
+		items = append(items, item)
+
+		return nil
+	})
+	return items, err
+}
+
+func loadTar(file string) ([]unstructured.Unstructured, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []unstructured.Unstructured
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg || !isBundleFile(hdr.Name) {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := decodeObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not load %v: %v", hdr.Name, err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func decodeObject(data []byte) (unstructured.Unstructured, error) {
+	var obj map[string]interface{}
+	err := yaml.Unmarshal(data, &obj)
+	return unstructured.Unstructured{Object: obj}, err
+}
@@ -0,0 +1,224 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type dumped struct {
+	key   string
+	dump  config.Dump
+	items []unstructured.Unstructured
+}
+
+// testDumps returns dumps of namespaced and cluster scoped resources, with an empty one in between
+func testDumps(t *testing.T) []dumped {
+	obj := func(s string) unstructured.Unstructured {
+		var o map[string]interface{}
+		err := json.Unmarshal([]byte(s), &o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return unstructured.Unstructured{Object: o}
+	}
+
+	return []dumped{
+		{
+			key:  "pods",
+			dump: config.Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, Namespace: "web"},
+			items: []unstructured.Unstructured{
+				obj(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-0", "namespace": "web", "labels": {"app": "web"}},
+					"spec": {"containers": [{"name": "app", "image": "nginx:1.21", "ports": [{"containerPort": 80}]}]}}`),
+				obj(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-1", "namespace": "web"},
+					"spec": {"containers": [{"name": "app", "image": "nginx:1.21", "args": ["--multi\nline", ""]}], "nodeName": null}}`),
+			},
+		},
+		{
+			key:  "secrets",
+			dump: config.Dump{GVR: schema.GroupVersionResource{Version: "v1", Resource: "secrets"}},
+		},
+		{
+			key:  "clusterroles",
+			dump: config.Dump{GVR: schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}},
+			items: []unstructured.Unstructured{
+				obj(`{"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRole", "metadata": {"name": "view"},
+					"rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get", "list"]}], "aggregationRule": null}`),
+			},
+		},
+	}
+}
+
+func printDumps(t *testing.T, p Printer, dumps []dumped) {
+	for _, d := range dumps {
+		err := p.Begin(d.key, d.dump)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range d.items {
+			err = p.Write(d.key, item)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err := p.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkLoaded(t *testing.T, dumps []dumped, got []unstructured.Unstructured) {
+	var want []unstructured.Unstructured
+	for _, d := range dumps {
+		want = append(want, d.items...)
+	}
+
+	// dumps printed as a map of keys and bundle files aren't loaded in the order they were written
+	sortItems := func(items []unstructured.Unstructured) {
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].GetNamespace()+"/"+items[i].GetName() < items[j].GetNamespace()+"/"+items[j].GetName()
+		})
+	}
+	sortItems(want)
+	sortItems(got)
+
+	if len(got) != len(want) {
+		t.Fatalf("loaded %v resources, want %v", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i].Object, want[i].Object) {
+			t.Errorf("loaded %v, want %v", got[i].Object, want[i].Object)
+		}
+	}
+}
+
+func TestLoadPrinted(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatYAML, FormatNDJSON, FormatList} {
+		t.Run(format, func(t *testing.T) {
+			dumps := testDumps(t)
+
+			var buf bytes.Buffer
+			p, err := New(format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			printDumps(t, p, dumps)
+
+			file := filepath.Join(t.TempDir(), "dump."+format)
+			err = ioutil.WriteFile(file, buf.Bytes(), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			items, err := Load(file)
+			if err != nil {
+				t.Fatalf("could not load %v output: %v\n%s", format, err, buf.String())
+			}
+			checkLoaded(t, dumps, items)
+		})
+	}
+}
+
+func TestLoadBundle(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		bundle func(path string) (*Bundle, error)
+	}{
+		{"dir", "bundle", func(path string) (*Bundle, error) { return NewDirBundle(path, "test") }},
+		{"tar", "bundle.tar.gz", func(path string) (*Bundle, error) { return NewTarBundle(path, "test") }},
+		{"tgz", "bundle.tgz", func(path string) (*Bundle, error) { return NewTarBundle(path, "test") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dumps := testDumps(t)
+			path := filepath.Join(t.TempDir(), tt.path)
+
+			b, err := tt.bundle(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			printDumps(t, b, dumps)
+
+			items, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkLoaded(t, dumps, items)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", data: "  \n"},
+		{name: "single json resource", data: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a"}}`, want: []string{"a"}},
+		{name: "single yaml resource", data: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\n", want: []string{"a"}},
+		{
+			name: "list",
+			data: `{"apiVersion": "v1", "kind": "List", "items": [{"metadata": {"name": "a"}}, {"metadata": {"name": "b"}}]}`,
+			want: []string{"a", "b"},
+		},
+		{
+			name: "typed list",
+			data: "apiVersion: v1\nkind: PodList\nitems:\n- metadata:\n    name: a\n",
+			want: []string{"a"},
+		},
+		{name: "dump map", data: `{"pods": [{"metadata": {"name": "a"}}], "secrets": null}`, want: []string{"a"}},
+		{
+			name: "ndjson",
+			data: `{"key": "pods", "object": {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a"}}}` + "\n\n" +
+				`{"key": "pods", "object": {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "b"}}}`,
+			want: []string{"a", "b"},
+		},
+		{
+			name: "single ndjson line",
+			data: `{"key": "pods", "object": {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a"}}}` + "\n",
+			want: []string{"a"},
+		},
+		{
+			name: "unwrapped ndjson",
+			data: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a"}}` + "\n" +
+				`{"key": "pods", "object": {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "b"}}}`,
+			want: []string{"a", "b"},
+		},
+		{name: "ndjson line that isn't a resource", data: `{"key": "pods", "object": {"metadata": {"name": "a"}}}` + "\n" + `{}`, wantErr: true},
+		{name: "dump that isn't a list", data: `{"pods": {"metadata": {"name": "a"}}}`, wantErr: true},
+		{name: "list of non objects", data: `{"pods": ["a"]}`, wantErr: true},
+		{name: "invalid", data: `{"pods": [`, wantErr: true},
+		{name: "invalid ndjson", data: `{"key": "pods"}` + "\n" + `{"key": `, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Decode([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, item := range items {
+				names = append(names, item.GetName())
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Decode() names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
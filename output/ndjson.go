@@ -0,0 +1,39 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// NDJSON prints one json object per line for every item, each wrapped with the key of its dump
+type NDJSON struct {
+	enc *json.Encoder
+}
+
+type ndjsonLine struct {
+	Key    string                 `json:"key"`
+	Object map[string]interface{} `json:"object"`
+}
+
+// NewNDJSON returns a NDJSON printer that writes to w
+func NewNDJSON(w io.Writer) *NDJSON {
+	return &NDJSON{enc: json.NewEncoder(w)}
+}
+
+// Begin is a no-op, ndjson lines carry their own key
+func (p *NDJSON) Begin(key string, dump config.Dump) error {
+	return nil
+}
+
+// Write prints an item on its own line
+func (p *NDJSON) Write(key string, item unstructured.Unstructured) error {
+	return p.enc.Encode(ndjsonLine{Key: key, Object: item.Object})
+}
+
+// Flush is a no-op, every line is complete once written
+func (p *NDJSON) Flush() error {
+	return nil
+}
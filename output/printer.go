@@ -0,0 +1,49 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ryansann/k8sutil/k8s"
+)
+
+// Output formats supported by New
+const (
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatNDJSON = "ndjson"
+	FormatTable  = "table"
+	FormatList   = "list"
+	FormatCSV    = "csv"
+)
+
+// Formats lists every supported output format
+var Formats = []string{FormatJSON, FormatYAML, FormatNDJSON, FormatTable, FormatList, FormatCSV}
+
+// Printer writes dumps to an output as their items are streamed from the cluster
+type Printer interface {
+	k8s.DumpWriter
+	// Flush completes the output once every dump has been written
+	Flush() error
+}
+
+// New returns a printer for format that writes to w
+func New(format string, w io.Writer) (Printer, error) {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return NewJSON(w), nil
+	case FormatYAML:
+		return NewYAML(w), nil
+	case FormatNDJSON:
+		return NewNDJSON(w), nil
+	case FormatTable:
+		return NewTable(w), nil
+	case FormatList:
+		return NewList(w), nil
+	case FormatCSV:
+		return NewCSV(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %q, must be one of: %v", format, strings.Join(Formats, ", "))
+	}
+}
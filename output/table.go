@@ -0,0 +1,69 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Table prints dumps in the style of kubectl get, one table per dump with the dump's columns
+type Table struct {
+	w       io.Writer
+	tw      *tabwriter.Writer
+	started bool
+	cols    []column
+}
+
+// NewTable returns a Table printer that writes to w
+func NewTable(w io.Writer) *Table {
+	return &Table{w: w}
+}
+
+// Begin flushes the previous dump's table and prints the header for the next one
+func (p *Table) Begin(key string, dump config.Dump) error {
+	if p.started {
+		err := p.tw.Flush()
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(p.w)
+		if err != nil {
+			return err
+		}
+	}
+	p.started = true
+
+	cols, err := columnsFor(dump)
+	if err != nil {
+		return err
+	}
+	p.cols = cols
+
+	p.tw = tabwriter.NewWriter(p.w, 0, 8, 3, ' ', 0)
+	_, err = fmt.Fprintln(p.tw, strings.Join(header(cols), "\t"))
+	return err
+}
+
+// Write prints a row for an item
+func (p *Table) Write(key string, item unstructured.Unstructured) error {
+	values, err := row(p.cols, item)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(p.tw, strings.Join(values, "\t"))
+	return err
+}
+
+// Flush flushes the last dump's table
+func (p *Table) Flush() error {
+	if !p.started {
+		return nil
+	}
+	return p.tw.Flush()
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ryansann/k8sutil/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// YAML prints dumps as a single yaml document keyed by dump
+type YAML struct {
+	w       io.Writer
+	started bool
+	empty   bool
+}
+
+// NewYAML returns a YAML printer that writes to w
+func NewYAML(w io.Writer) *YAML {
+	return &YAML{w: w}
+}
+
+// Begin starts the yaml sequence for a dump
+func (p *YAML) Begin(key string, dump config.Dump) error {
+	err := p.closeSeq()
+	if err != nil {
+		return err
+	}
+	p.started = true
+	p.empty = true
+
+	k, err := yaml.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(p.w, "%s:", strings.TrimSuffix(string(k), "\n"))
+	return err
+}
+
+// Write appends an item to the current dump's sequence
+func (p *YAML) Write(key string, item unstructured.Unstructured) error {
+	b, err := yaml.Marshal(item.Object)
+	if err != nil {
+		return err
+	}
+	p.empty = false
+
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	for i, line := range lines {
+		prefix := "  "
+		if i == 0 {
+			prefix = "- "
+		}
+		lines[i] = prefix + line
+	}
+
+	_, err = fmt.Fprintf(p.w, "\n%s", strings.Join(lines, "\n"))
+	return err
+}
+
+// Flush terminates the last sequence
+func (p *YAML) Flush() error {
+	if !p.started {
+		_, err := fmt.Fprintln(p.w, "{}")
+		return err
+	}
+	return p.closeSeq()
+}
+
+func (p *YAML) closeSeq() error {
+	if !p.started {
+		return nil
+	}
+
+	end := "\n"
+	if p.empty {
+		end = " []\n"
+	}
+
+	_, err := io.WriteString(p.w, end)
+	return err
+}